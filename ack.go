@@ -0,0 +1,46 @@
+package queue
+
+/*
+ @File : ack.go
+ @Description: at-least-once consume, the item only leave the queue after processed successfully
+ @Time : 2026/10/15
+*/
+
+// ConsumeAck pass the head item to fn, the item is dequeued only when fn return nil,
+// if fn return error the item stays at the head and the next ConsumeAck will get it again.
+// Return acked true if an item is processed and dequeued, false with nil error if the queue is empty.
+// The tombstones left by SafePut and the positions given up by WithDropAfter are taken out without calling fn,
+// then the item behind them is passed to fn.
+//
+// The ring can't hold the head while other consumers are taking items,
+// so ConsumeAck must be the ONLY consumer of the queue, don't mix it with Get from other goroutines,
+// ErrConcurrentConsumer is returned if the head is taken by another consumer while fn is running,
+// fn has processed the item but it is not acked here
+func (q *DefaultQueue) ConsumeAck(fn func(val interface{}) error) (acked bool, err error) {
+	for {
+		read := q.read.Load()
+		write := q.write.Load()
+		if q.posCount(read, write) < 1 {
+			return false, nil
+		}
+
+		getPosNext := read + 1
+		val := q.peekAt(getPosNext)
+		skip := val == nil || val == tombstone
+		if !skip {
+			if err := fn(val); err != nil {
+				return false, err
+			}
+		}
+
+		// 单消费者时这里的占位不会失败，失败说明违反了约定，这个位置已经属于别的消费者，不能再取
+		if !q.casRead(read, getPosNext) {
+			return false, ErrConcurrentConsumer
+		}
+		q.getAt(getPosNext)
+		if !skip {
+			return true, nil
+		}
+		// 没有数据的槽取走后接着看下一个
+	}
+}
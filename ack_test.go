@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConsumeAckSuccess(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.PutSlice(ints(2))
+
+	var got interface{}
+	acked, err := q.ConsumeAck(func(val interface{}) error {
+		got = val
+		return nil
+	})
+	if !acked || err != nil || got != 0 {
+		t.Fatalf("acked %v err %v val %v, want item 0 acked", acked, err, got)
+	}
+	if val, _, _ := q.Get(); val != 1 {
+		t.Fatalf("head is %v after ack, want 1", val)
+	}
+	if acked, err := q.ConsumeAck(func(interface{}) error { return nil }); acked || err != nil {
+		t.Fatalf("acked %v err %v on empty queue", acked, err)
+	}
+}
+
+func TestConsumeAckRetry(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.PutSlice(ints(2))
+
+	failure := errors.New("process failed")
+	for i := 0; i < 3; i++ {
+		acked, err := q.ConsumeAck(func(val interface{}) error {
+			if val != 0 {
+				t.Fatalf("attempt %d got %v, want the same head 0", i, val)
+			}
+			return failure
+		})
+		if acked || err != failure {
+			t.Fatalf("acked %v err %v, want the error of fn", acked, err)
+		}
+	}
+	if q.Count() != 2 {
+		t.Fatalf("count %d after failures, want 2", q.Count())
+	}
+	if acked, _ := q.ConsumeAck(func(interface{}) error { return nil }); !acked {
+		t.Fatal("retry not acked")
+	}
+	assertSeq(t, drainAll(t, q), 1, 1)
+}
+
+func TestConsumeAckConcurrentConsumer(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.PutSlice(ints(2))
+
+	// 处理期间队头被别的消费者取走
+	acked, err := q.ConsumeAck(func(interface{}) error {
+		q.Get()
+		return nil
+	})
+	if acked || !errors.Is(err, ErrConcurrentConsumer) {
+		t.Fatalf("acked %v err %v, want ErrConcurrentConsumer", acked, err)
+	}
+	// 没有多取，剩下的还在
+	assertSeq(t, drainAll(t, q), 1, 1)
+}
+
+func TestConsumeAckSkipTombstone(t *testing.T) {
+	q := newDefaultQueue(8)
+	if ok, _, err := q.SafePut(func() interface{} { panic("boom") }); ok || err == nil {
+		t.Fatalf("safe put ok %v err %v, want the panic error", ok, err)
+	}
+	q.Put(1)
+
+	// SafePut 失败留下的 tombstone 不交给 fn，直接取走，处理后面的数据
+	var got []interface{}
+	acked, err := q.ConsumeAck(func(val interface{}) error {
+		got = append(got, val)
+		return nil
+	})
+	if !acked || err != nil || len(got) != 1 || got[0] != 1 {
+		t.Fatalf("acked %v err %v got %v, want only 1", acked, err, got)
+	}
+	if cnt := q.Count(); cnt != 0 {
+		t.Fatalf("count %d, want 0", cnt)
+	}
+}
+
+func TestConsumeAckDropAfter(t *testing.T) {
+	q := newDefaultQueue(8, WithDropAfter(16))
+	token, _ := q.Reserve()
+	q.Put(2)
+
+	// 占了位置一直不写的生产者不会让 ConsumeAck 一直等下去
+	var got interface{}
+	acked, err := q.ConsumeAck(func(val interface{}) error {
+		got = val
+		return nil
+	})
+	if !acked || err != nil || got != 2 {
+		t.Fatalf("acked %v err %v got %v, want 2", acked, err, got)
+	}
+	token.Commit(1) // 被放弃的位置，写入的数据丢弃
+	if acked, err := q.ConsumeAck(func(interface{}) error { return nil }); acked || err != nil {
+		t.Fatalf("acked %v err %v on the dropped position, want empty", acked, err)
+	}
+}
@@ -0,0 +1,52 @@
+package queue
+
+/*
+ @File : adaptive.go
+ @Description: put with adaptive backpressure by the consumption rate
+ @Time : 2026/10/15
+*/
+
+import "time"
+
+// adaptiveMaxWait PutAdaptive 最多等待的时间，消费间隔比这个还长就直接失败
+const adaptiveMaxWait = 10 * time.Millisecond
+
+// PutAdaptive the same as Put when there is room, if the queue is full,
+// keep retrying for about two average consume intervals when the consumers are keeping up,
+// and fail fast when they are not, that is no item taken for several intervals or the interval is too long.
+// Need WithRateTracking, otherwise it's the same as Put
+func (q *DefaultQueue) PutAdaptive(val interface{}) (ok bool, count uint32) {
+	if ok, count = q.Put(val); ok || !q.rate {
+		return ok, count
+	}
+
+	interval := time.Duration(q.getInterval.Load())
+	if interval <= 0 || interval > adaptiveMaxWait {
+		return false, count
+	}
+	// 距离上次取数据已经过去好几个间隔了，消费者大概率卡住了
+	now := time.Now()
+	if now.Sub(time.Unix(0, q.lastGet.Load())) > 4*interval {
+		return false, count
+	}
+
+	deadline := now.Add(2 * interval)
+	for time.Now().Before(deadline) {
+		if ok, count = q.Put(val); ok {
+			return ok, count
+		}
+	}
+	return false, count
+}
+
+// PutOrWaitHint the same as Put, and when rejected because the queue is full,
+// suggest how long to wait before retrying, that is the average consume interval, the time one slot takes to free up.
+// suggestedWait is 0 when ok, when rejected for lock slot failed (retry at once),
+// when the queue is closed, or when the rate is unknown: without WithRateTracking or nothing taken yet
+func (q *DefaultQueue) PutOrWaitHint(val interface{}) (ok bool, suggestedWait time.Duration) {
+	ok, count := q.Put(val)
+	if ok || !q.rate || count < q.usable() || q.closed.Load() {
+		return ok, 0
+	}
+	return false, time.Duration(q.getInterval.Load())
+}
@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+// measureRate 以 interval 的间隔取 n 次，让队列记录消费速度
+func measureRate(q *DefaultQueue, n int, interval time.Duration) {
+	for i := 0; i < n; i++ {
+		q.Put(i)
+		q.Get()
+		time.Sleep(interval)
+	}
+}
+
+func TestPutAdaptiveFastConsumer(t *testing.T) {
+	q := newDefaultQueue(8, WithRateTracking())
+	measureRate(q, 20, 200*time.Microsecond)
+	q.PutSlice(ints(6))
+
+	// 消费者跟得上，满的时候等一会就能放入
+	go func() {
+		time.Sleep(50 * time.Microsecond)
+		q.Get()
+	}()
+	if ok, _ := q.PutAdaptive(-1); !ok {
+		t.Fatal("adaptive put failed while the consumer is keeping up")
+	}
+}
+
+func TestPutAdaptiveStalledConsumer(t *testing.T) {
+	q := newDefaultQueue(8, WithRateTracking())
+	measureRate(q, 20, 100*time.Microsecond)
+	q.PutSlice(ints(6))
+
+	// 消费者已经好几个间隔没有取了，马上失败
+	time.Sleep(10 * time.Millisecond)
+	start := time.Now()
+	if ok, _ := q.PutAdaptive(-1); ok {
+		t.Fatal("adaptive put succeeded on a full queue")
+	}
+	if d := time.Since(start); d > time.Millisecond {
+		t.Fatalf("waited %v for a stalled consumer, want fail fast", d)
+	}
+}
+
+func TestPutAdaptiveWithoutRate(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.PutSlice(ints(6))
+	if ok, cnt := q.PutAdaptive(-1); ok || cnt != 6 {
+		t.Fatalf("ok %v count %d, want the same as Put", ok, cnt)
+	}
+	q.Get()
+	if ok, _ := q.PutAdaptive(-1); !ok {
+		t.Fatal("adaptive put failed with room")
+	}
+}
+
+func TestPutOrWaitHint(t *testing.T) {
+	q := newDefaultQueue(8, WithRateTracking())
+	// 没取过数据，速度未知
+	q.PutSlice(ints(6))
+	if ok, wait := q.PutOrWaitHint(-1); ok || wait != 0 {
+		t.Fatalf("put %v wait %v before any rate measured", ok, wait)
+	}
+	drainAll(t, q)
+
+	// 消费者每 2ms 取一个，满了之后建议等一个间隔左右
+	const cadence = 2 * time.Millisecond
+	measureRate(q, 30, cadence)
+	q.PutSlice(ints(6))
+	ok, wait := q.PutOrWaitHint(-1)
+	if ok {
+		t.Fatal("put into a full queue succeeded")
+	}
+	// Sleep 只会多睡不会少睡，上限给调度留足余量
+	if wait < cadence*3/4 || wait > cadence*10 {
+		t.Fatalf("suggested wait %v with a consume interval of %v", wait, cadence)
+	}
+
+	// 有空间时直接放入，不需要等
+	q.Get()
+	if ok, wait := q.PutOrWaitHint(-1); !ok || wait != 0 {
+		t.Fatalf("put %v wait %v with room", ok, wait)
+	}
+	// 关闭后不建议等
+	q.Close()
+	if ok, wait := q.PutOrWaitHint(-1); ok || wait != 0 {
+		t.Fatalf("put %v wait %v on a closed queue", ok, wait)
+	}
+}
+
+func TestPutOrWaitHintWithoutRate(t *testing.T) {
+	q := newDefaultQueue(8)
+	measureRate(q, 5, time.Millisecond)
+	q.PutSlice(ints(6))
+	if ok, wait := q.PutOrWaitHint(-1); ok || wait != 0 {
+		t.Fatalf("put %v wait %v without WithRateTracking", ok, wait)
+	}
+}
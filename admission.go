@@ -0,0 +1,128 @@
+package queue
+
+/*
+ @File : admission.go
+ @Description: queue which reject new items when near full, protect latency at saturation
+ @Time : 2026/10/15
+*/
+
+import (
+	"go.uber.org/atomic"
+)
+
+// AdmitStatus result of AdmissionQueue.Admit
+type AdmitStatus int
+
+const (
+	Accepted AdmitStatus = iota // 放入成功
+	Rejected                    // 利用率超过水位被拒绝，队列中物理上可能还有空间
+	Failed                      // 和 Put 失败一样，队列满、关闭或者占位失败，可以重试
+)
+
+// AdmissionQueue reject Put once the utilization exceeds the high watermark,
+// and accept again only after the utilization falls below the low watermark,
+// the gap between them avoid flapping around one line.
+// The ring is not exposed, so every item goes through the watermark check
+type AdmissionQueue struct {
+	ring      *DefaultQueue
+	high      float64
+	low       float64
+	rejecting *atomic.Bool // 是否处于拒绝状态
+}
+
+// NewAdmissionQueue alloc an AdmissionQueue, highWatermark is the utilization in (0, 1],
+// utilization is count / usable capacity, the low watermark is 3/4 of high by default, see SetLowWatermark
+func NewAdmissionQueue(cap uint32, highWatermark float64, opts ...Option) *AdmissionQueue {
+	q := &AdmissionQueue{
+		ring:      newDefaultQueue(cap, opts...),
+		high:      highWatermark,
+		low:       highWatermark * 3 / 4,
+		rejecting: atomic.NewBool(false),
+	}
+	q.ring.registerAs(q)
+	return q
+}
+
+// SetLowWatermark change the low watermark, should be less than the high one,
+// not concurrent safe, call it before use
+func (q *AdmissionQueue) SetLowWatermark(low float64) {
+	q.low = low
+}
+
+// Put the same as Admit, both Rejected and Failed return false
+func (q *AdmissionQueue) Put(val interface{}) (ok bool, count uint32) {
+	status, count := q.Admit(val)
+	return status == Accepted, count
+}
+
+// Admit put val into queue if the utilization allowed
+func (q *AdmissionQueue) Admit(val interface{}) (status AdmitStatus, count uint32) {
+	admitted, cnt := q.admit()
+	if !admitted {
+		return Rejected, cnt
+	}
+	if ok, count := q.ring.Put(val); ok {
+		return Accepted, count
+	}
+	return Failed, cnt
+}
+
+// admit 按水位判断是否接收，并更新拒绝状态
+func (q *AdmissionQueue) admit() (ok bool, count uint32) {
+	cnt := q.ring.Count()
+	utilization := float64(cnt) / float64(q.ring.capMod-1)
+
+	if q.rejecting.Load() {
+		// 低于低水位才恢复接收
+		if utilization >= q.low {
+			return false, cnt
+		}
+		q.rejecting.Store(false)
+	} else if utilization > q.high {
+		q.rejecting.Store(true)
+		return false, cnt
+	}
+	return true, cnt
+}
+
+// reserveSlot 和 Admit 一样先检查水位，见 Transfer
+func (q *AdmissionQueue) reserveSlot() (token *Token, full bool) {
+	if ok, _ := q.admit(); !ok {
+		return nil, true
+	}
+	return q.ring.reserveSlot()
+}
+
+// Get the same as DefaultQueue.Get
+func (q *AdmissionQueue) Get() (val interface{}, ok bool, count uint32) {
+	return q.ring.Get()
+}
+
+// Count the number of items in queue
+func (q *AdmissionQueue) Count() uint32 {
+	return q.ring.Count()
+}
+
+// Capacity the allocated size of queue, see DefaultQueue.Capacity
+func (q *AdmissionQueue) Capacity() uint32 {
+	return q.ring.Capacity()
+}
+
+// Info the same as DefaultQueue.Info
+func (q *AdmissionQueue) Info() string {
+	return q.ring.Info()
+}
+
+// Close stop accepting new items, see DefaultQueue.Close
+func (q *AdmissionQueue) Close() error {
+	return q.ring.Close()
+}
+
+// space 按水位会被拒绝时没有空间，否则同 DefaultQueue，见 Partition
+func (q *AdmissionQueue) space() uint32 {
+	utilization := float64(q.ring.Count()) / float64(q.ring.capMod-1)
+	if rejecting := q.rejecting.Load(); rejecting && utilization >= q.low || !rejecting && utilization > q.high {
+		return 0
+	}
+	return q.ring.space()
+}
@@ -0,0 +1,50 @@
+package queue
+
+import "testing"
+
+func TestAdmissionHysteresis(t *testing.T) {
+	q := NewAdmissionQueue(16, 0.5) // 最多 14 个，超过 7 个拒绝，低水位 0.375，5 个及以下恢复
+	for i := 0; i < 8; i++ {
+		if status, _ := q.Admit(i); status != Accepted {
+			t.Fatalf("item %d: status %d below high watermark", i, status)
+		}
+	}
+	if status, cnt := q.Admit(8); status != Rejected || cnt != 8 {
+		t.Fatalf("status %d count %d above high watermark, want Rejected", status, cnt)
+	}
+
+	// 高低水位之间仍然拒绝
+	for q.Count() > 6 {
+		q.Get()
+	}
+	if ok, _ := q.Put(8); ok {
+		t.Fatal("accepted between the watermarks while rejecting")
+	}
+	// 低于低水位恢复
+	q.Get()
+	if status, _ := q.Admit(8); status != Accepted {
+		t.Fatalf("status %d below low watermark, want Accepted", status)
+	}
+	// 恢复后在高低水位之间继续接收，直到再次超过高水位
+	for q.Count() <= 7 {
+		if status, _ := q.Admit(0); status != Accepted {
+			t.Fatalf("status %d at count %d after resume", status, q.Count())
+		}
+	}
+	if status, _ := q.Admit(0); status != Rejected {
+		t.Fatalf("status %d above high watermark again", status)
+	}
+}
+
+func TestAdmissionTransferRejecting(t *testing.T) {
+	dst := NewAdmissionQueue(16, 0.1)
+	src := newDefaultQueue(16)
+	src.PutSlice(ints(5))
+	// 超过高水位后 dst 拒绝，Transfer 停下来而不是一直重试
+	if n := Transfer(dst, src, 5); n != 2 {
+		t.Fatalf("transferred %d, want 2", n)
+	}
+	if dst.Count()+src.Count() != 5 {
+		t.Fatalf("items lost: %d in dst, %d in src", dst.Count(), src.Count())
+	}
+}
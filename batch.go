@@ -0,0 +1,275 @@
+package queue
+
+/*
+ @File : batch.go
+ @Description: batch operations, reserve several positions with one CAS then fill them one by one
+ @Time : 2026/10/15
+*/
+
+import (
+	"runtime"
+	"time"
+)
+
+// PutSlice put vals into queue in order, and return how many enqueued.
+// Only one CAS to reserve the positions, if there is not enough room for the whole slice,
+// only the leading portion which fits is enqueued, that is vals[:enqueued],
+// the caller should deal with vals[enqueued:] by itself.
+// Return 0 if the queue is full or closed or lock positions failed.
+func (q *DefaultQueue) PutSlice(vals []interface{}) (enqueued int) {
+	puts, _ := q.Puts(vals)
+	return int(puts)
+}
+
+// Puts put values into queue in order with one CAS, return how many put and the count after put,
+// only values[:puts] are put if there is not enough room, see PutSlice
+func (q *DefaultQueue) Puts(values []interface{}) (puts, count uint32) {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := q.posCount(read, write)
+	if len(values) == 0 || q.closed.Load() {
+		return 0, cnt
+	}
+	if cnt >= q.usable() {
+		q.onFull(cnt)
+		q.yield()
+		return 0, cnt
+	}
+	// 磁盘上还有溢出的数据，直接写入环形队列会打乱顺序，当作满处理
+	if q.spill != nil && q.spill.len() > 0 {
+		return 0, cnt
+	}
+
+	n := q.reservePut(write, cnt, uint32(len(values)))
+	if n == 0 {
+		q.yield()
+		return 0, cnt
+	}
+	for i := uint32(0); i < n; i++ {
+		q.track(write+1+i, "")
+		q.putAt(write+1+i, entry{value: values[i]})
+	}
+	q.onPut(n)
+	return n, cnt + n
+}
+
+// TryPutN put all vals in order with one CAS, or none of them if there is not room for the whole batch,
+// unlike Puts which put the leading portion that fits. Never spin or retry,
+// ok false if there is not room for all, closed, items are spilled to disk (see WithSpillover) or lock positions failed.
+// An empty vals is always put unless closed
+func (q *DefaultQueue) TryPutN(vals []interface{}) (ok bool, count uint32) {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := q.posCount(read, write)
+	if q.closed.Load() || (q.spill != nil && q.spill.len() > 0) {
+		return false, cnt
+	}
+	n := uint32(len(vals))
+	if n == 0 {
+		return true, cnt
+	}
+	usable := q.usable()
+	if cnt >= usable {
+		q.onFull(cnt)
+		q.yield()
+		return false, cnt
+	}
+	// 放不下全部就一个都不放
+	if n > usable-cnt || !q.casWrite(write, write+n) {
+		q.yield()
+		return false, cnt
+	}
+	for i := uint32(0); i < n; i++ {
+		q.track(write+1+i, "")
+		q.putAt(write+1+i, entry{value: vals[i]})
+	}
+	q.onPut(n)
+	return true, cnt + n
+}
+
+// Gets get items into values in order with one CAS, return how many got and the count after get,
+// get min(len(values), count) items, values[gets:] is not touched.
+// The reserved slots are contiguous in physical index order, split into two runs only when they wrap
+// the end of carrier, and values are always in logical order
+func (q *DefaultQueue) Gets(values []interface{}) (gets, count uint32) {
+	if q.spill != nil && q.spill.len() > 0 {
+		q.refill()
+	}
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := q.posCount(read, write)
+	n := q.reserveGet(read, cnt, uint32(len(values)))
+	if n == 0 {
+		if cnt == 0 {
+			q.onEmpty()
+		}
+		q.yield()
+		return 0, cnt
+	}
+	// 按逻辑顺序逐个取就是按物理下标顺序访问，只在回绕处分成两段，
+	// 每个槽都要单独等待写入完成，按段处理没有收益（见 BenchmarkGetsSegmented），保持逐个取
+	for i := uint32(0); i < n; i++ {
+		values[i] = q.getAt(read + 1 + i)
+	}
+	return n, cnt - n
+}
+
+// GetBatchWithCount get up to max items in order with one CAS, and the count still in queue after get,
+// so the consumer can decide whether to loop again without calling Count.
+// vals is empty if the queue is empty or lock positions failed
+func (q *DefaultQueue) GetBatchWithCount(max int) (vals []interface{}, remaining uint32) {
+	if max <= 0 {
+		return nil, q.Count()
+	}
+	if q.spill != nil && q.spill.len() > 0 {
+		q.refill()
+	}
+	// 按当前数量分配，避免 max 很大而队列里只有几个时浪费
+	n := q.Count()
+	if uint32(max) < n {
+		n = uint32(max)
+	}
+	if n == 0 {
+		return nil, 0
+	}
+	vals = make([]interface{}, n)
+	gets, remaining := q.Gets(vals)
+	return vals[:gets], remaining
+}
+
+// TakeAll take all the items in queue at the moment with one CAS moving read to write, and return them in order,
+// the slots are cleared. The items put after the CAS are left for the next time,
+// the ones reserved before it but not written yet are waited for. Empty if the queue is empty
+func (q *DefaultQueue) TakeAll() []interface{} {
+	if q.spill != nil && q.spill.len() > 0 {
+		q.refill()
+	}
+	for {
+		read := q.read.Load()
+		write := q.write.Load()
+		cnt := q.posCount(read, write)
+		if cnt == 0 {
+			return nil
+		}
+		if !q.casRead(read, read+cnt) {
+			runtime.Gosched()
+			continue // 被其他消费者取走了一部分，重新看剩下的
+		}
+		vals := make([]interface{}, 0, cnt)
+		for i := uint32(0); i < cnt; i++ {
+			if val := q.getAt(read + 1 + i); val != nil {
+				vals = append(vals, val)
+			}
+		}
+		return vals
+	}
+}
+
+// GetBatchInto fill dst with items in order until dst is full or the queue is empty, return how many filled.
+// Unlike Gets it retries when lock positions failed, and never alloc, dst can be reused across calls
+func (q *DefaultQueue) GetBatchInto(dst []interface{}) int {
+	filled := 0
+	for filled < len(dst) {
+		n, cnt := q.Gets(dst[filled:])
+		filled += int(n)
+		if n == 0 && cnt == 0 {
+			break
+		}
+	}
+	return filled
+}
+
+// RetryPuts keep putting the rest of vals for retry more rounds with backoff between them,
+// return how many put in total, vals[:puts] are put in order
+func (q *DefaultQueue) RetryPuts(vals []interface{}, retry uint32) (puts uint32) {
+	for i := uint32(0); ; i++ {
+		n, _ := q.Puts(vals[puts:])
+		puts += n
+		if int(puts) == len(vals) || i >= retry {
+			return puts
+		}
+		backoff(i)
+	}
+}
+
+// RetryGets keep getting into the rest of dst for retry more rounds with backoff between them,
+// return how many got in total, dst[:gets] are filled in order
+func (q *DefaultQueue) RetryGets(dst []interface{}, retry uint32) (gets uint32) {
+	for i := uint32(0); ; i++ {
+		n, _ := q.Gets(dst[gets:])
+		gets += n
+		if int(gets) == len(dst) || i >= retry {
+			return gets
+		}
+		backoff(i)
+	}
+}
+
+// backoff 第 i 次重试失败后的等待，前几次只让出 cpu，之后睡眠时间指数增加，最多 1ms
+func backoff(i uint32) {
+	if i < 4 {
+		runtime.Gosched()
+		return
+	}
+	d := time.Millisecond
+	if i-4 < 10 {
+		d = time.Microsecond << (i - 4)
+	}
+	time.Sleep(d)
+}
+
+// reservePut 一次 CAS 占用 write 之后的 min(want, 剩余空间) 个位置，返回占到的个数，失败返回 0
+// 占到的位置为 write+1 ~ write+n，需要调用方逐个 putAt
+func (q *DefaultQueue) reservePut(write, cnt, want uint32) uint32 {
+	usable := q.usable()
+	if cnt >= usable {
+		return 0
+	}
+	free := usable - cnt // 与 Put 中的满判断保持一致，最多只能放 capMod-1 个，再减去预留的
+	n := want
+	if n > free {
+		n = free
+	}
+	if n == 0 || !q.casWrite(write, write+n) {
+		return 0
+	}
+	return n
+}
+
+// Skip drop up to n items from the head without return them, the slots are cleared,
+// return how many skipped, for the consumer which want to jump forward when replay.
+// Return 0 if the queue is empty or lock positions failed
+func (q *DefaultQueue) Skip(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := q.posCount(read, write)
+	m := q.reserveGet(read, cnt, uint32(n))
+	if m == 0 {
+		q.yield()
+		return 0
+	}
+	for i := uint32(0); i < m; i++ {
+		q.getAt(read + 1 + i)
+	}
+	return int(m)
+}
+
+// reserveGet 一次 CAS 占用 read 之后的 min(want, cnt) 个位置，返回占到的个数，失败返回 0
+// 占到的位置为 read+1 ~ read+n，需要调用方逐个 getAt
+func (q *DefaultQueue) reserveGet(read, cnt, want uint32) uint32 {
+	n := want
+	if n > cnt {
+		n = cnt
+	}
+	if n == 0 || !q.casRead(read, read+n) {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,134 @@
+package queue
+
+/*
+ @File : bloom.go
+ @Description: approximate dedup by a bloom filter of the item keys, cheaper than the map of DedupQueue
+ @Time : 2026/10/15
+*/
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"go.uber.org/atomic"
+)
+
+// BloomDedupQueue skip Put if the bloom filter says the key of the item was probably put before.
+// Lock free and fixed memory, but approximate:
+// a unique item may be dropped as a false positive with about the fp rate given to NewBloomDedupQueue,
+// and two concurrent Put of the same item may both be enqueued.
+// A bloom filter can't delete, the filter is reset when Get takes the last item (the queue is drained),
+// so the dedup covers the items since the last drain, see ResetFilter.
+// The key is the value itself for string, []byte and fmt.Stringer, otherwise fmt.Sprint(val)
+type BloomDedupQueue struct {
+	ring *DefaultQueue
+	bits []atomic.Uint64 // 位图
+	m    uint64          // 位数
+	k    uint64          // 哈希函数个数
+}
+
+// NewBloomDedupQueue alloc a BloomDedupQueue whose filter holds expectedItems keys with false positive rate fp
+func NewBloomDedupQueue(cap uint32, expectedItems int, fp float64) *BloomDedupQueue {
+	if expectedItems < 1 {
+		expectedItems = 1
+	}
+	if fp <= 0 || fp >= 1 {
+		fp = 0.01
+	}
+	// m = -n*ln(p)/(ln2)^2，k = m/n*ln2
+	n := float64(expectedItems)
+	m := uint64(math.Ceil(-n * math.Log(fp) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / n * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &BloomDedupQueue{
+		ring: newDefaultQueue(cap),
+		bits: make([]atomic.Uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// Put May failed if the key was probably put before, or lock slot failed or full
+func (q *BloomDedupQueue) Put(val interface{}) (ok bool, count uint32) {
+	h1, h2 := bloomHash(val)
+	if q.contains(h1, h2) {
+		return false, q.ring.Count()
+	}
+	if ok, count = q.ring.Put(val); ok {
+		// 放入成功才记录，失败的以后还可以再放
+		q.add(h1, h2)
+	}
+	return ok, count
+}
+
+// Get get the head item, reset the filter when it takes the last one
+func (q *BloomDedupQueue) Get() (val interface{}, ok bool, count uint32) {
+	val, ok, count = q.ring.Get()
+	if ok && count == 0 {
+		q.ResetFilter()
+	}
+	return val, ok, count
+}
+
+// Count the number of items in queue
+func (q *BloomDedupQueue) Count() uint32 {
+	return q.ring.Count()
+}
+
+// ResetFilter forget all the keys, the items put before can be put again
+func (q *BloomDedupQueue) ResetFilter() {
+	for i := range q.bits {
+		q.bits[i].Store(0)
+	}
+}
+
+// contains 所有 k 个位都是 1 才可能存在
+func (q *BloomDedupQueue) contains(h1, h2 uint64) bool {
+	for i := uint64(0); i < q.k; i++ {
+		b := (h1 + i*h2) % q.m
+		if q.bits[b/64].Load()&(1<<(b%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// add 把 k 个位置 1
+func (q *BloomDedupQueue) add(h1, h2 uint64) {
+	for i := uint64(0); i < q.k; i++ {
+		b := (h1 + i*h2) % q.m
+		addr := &q.bits[b/64]
+		mask := uint64(1) << (b % 64)
+		for {
+			old := addr.Load()
+			if old&mask != 0 || addr.CAS(old, old|mask) {
+				break
+			}
+		}
+	}
+}
+
+// bloomHash 一次 fnv 得到两个哈希值，第 i 个哈希函数为 h1 + i*h2
+func bloomHash(val interface{}) (h1, h2 uint64) {
+	h := fnv.New64a()
+	switch v := val.(type) {
+	case string:
+		h.Write([]byte(v))
+	case []byte:
+		h.Write(v)
+	case fmt.Stringer:
+		h.Write([]byte(v.String()))
+	default:
+		fmt.Fprint(h, val)
+	}
+	sum := h.Sum64()
+	h1 = sum & 0xffffffff
+	h2 = sum>>32 | 1 // 奇数，避免 h2 为 0 时所有哈希函数相同
+	return h1, h2
+}
@@ -0,0 +1,73 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomDedupQueue(t *testing.T) {
+	q := NewBloomDedupQueue(1024, 1000, 0.001)
+	// 远少于预期的个数，误判率很低，不同的数据都放入
+	for i := 0; i < 200; i++ {
+		if ok, _ := q.Put(fmt.Sprintf("key-%d", i)); !ok {
+			t.Fatalf("unique key-%d dropped", i)
+		}
+	}
+	// 布隆过滤器没有漏判，重复的一定跳过
+	for i := 0; i < 200; i++ {
+		if ok, _ := q.Put(fmt.Sprintf("key-%d", i)); ok {
+			t.Fatalf("duplicate key-%d enqueued", i)
+		}
+	}
+	if q.Count() != 200 {
+		t.Fatalf("count %d, want 200", q.Count())
+	}
+
+	// 取走一部分，没有取空之前仍然去重
+	q.Get()
+	if ok, _ := q.Put("key-0"); ok {
+		t.Fatal("key-0 enqueued again before drained")
+	}
+	// 取空后过滤器重置，可以再放
+	for q.Count() > 0 {
+		q.Get()
+	}
+	if ok, _ := q.Put("key-0"); !ok {
+		t.Fatal("key-0 dropped after drained")
+	}
+}
+
+func TestBloomDedupQueueFalsePositiveRate(t *testing.T) {
+	const n, fp = 1000, 0.01
+	q := NewBloomDedupQueue(2048, n, fp)
+	for i := 0; i < n; i++ {
+		q.Put(i)
+	}
+	// 放满预期个数后，没放过的数据被误判的比例接近 fp
+	dropped := 0
+	const probes = 10000
+	for i := n; i < n+probes; i++ {
+		h1, h2 := bloomHash(i)
+		if q.contains(h1, h2) {
+			dropped++
+		}
+	}
+	if rate := float64(dropped) / probes; rate > 3*fp {
+		t.Fatalf("false positive rate %.4f, want about %.2f", rate, fp)
+	}
+}
+
+func TestBloomDedupQueueFullNotRecorded(t *testing.T) {
+	q := NewBloomDedupQueue(8, 100, 0.01)
+	for i := 0; i < 6; i++ {
+		q.Put(i)
+	}
+	// 满了放入失败的不记录，有空间后还能放
+	if ok, _ := q.Put("late"); ok {
+		t.Fatal("put into a full queue succeeded")
+	}
+	q.Get()
+	if ok, _ := q.Put("late"); !ok {
+		t.Fatal("late dropped as a duplicate after a failed put")
+	}
+}
@@ -0,0 +1,97 @@
+package queue
+
+/*
+ @File : callback.go
+ @Description: hooks triggered by Put/Get, all of them are optional, do nothing if not set
+ @Time : 2026/10/15
+*/
+
+import "time"
+
+// onFull Put 遇到队列满时调用，连续满到 FullStreak 次时触发一次回调
+func (q *DefaultQueue) onFull(cnt uint32) {
+	if q.stats {
+		q.count(q.metrics.full, 1)
+	}
+	if q.spaceCallback != nil && !q.wasFull.Load() {
+		q.wasFull.Store(true)
+	}
+	if q.fullCallback == nil {
+		return
+	}
+	if q.fullStreak.Inc() == FullStreak {
+		q.fullCallback(cnt)
+	}
+}
+
+// onPut 放入 n 个数据后调用，统计写入个数，结束队列满的连续计数
+func (q *DefaultQueue) onPut(n uint32) {
+	q.countPuts(n)
+	// 放满时记下，之后第一个 Get 触发 spaceCallback
+	if q.spaceCallback != nil && q.Count() >= q.capMod-1 && !q.wasFull.Load() {
+		q.wasFull.Store(true)
+	}
+	if q.fullCallback == nil {
+		return
+	}
+	if q.fullStreak.Load() != 0 {
+		q.fullStreak.Store(0)
+	}
+}
+
+// onEmpty Get 遇到队列空时调用，连续空到 EmptyStreak 次时触发一次回调
+func (q *DefaultQueue) onEmpty() {
+	if q.stats {
+		q.count(q.metrics.empty, 1)
+	}
+	if q.emptyCallback == nil {
+		return
+	}
+	if q.emptyStreak.Inc() == EmptyStreak {
+		q.emptyCallback()
+	}
+}
+
+// onSpace 槽被释放后调用，队列满过则触发一次 spaceCallback，CAS 保证并发的 Get 只有一个触发
+func (q *DefaultQueue) onSpace() {
+	if q.spaceCallback == nil || !q.wasFull.Load() {
+		return
+	}
+	if q.wasFull.CAS(true, false) {
+		q.spaceCallback()
+	}
+}
+
+// sampleLatency 是否对本次操作计时
+func (q *DefaultQueue) sampleLatency() bool {
+	if q.latencyEvery == 1 {
+		return true
+	}
+	return q.latencyCounter.Inc()%q.latencyEvery == 0
+}
+
+// onGet 槽被释放后调用，结束队列空的连续计数，更新取数据的平均间隔
+func (q *DefaultQueue) onGet() {
+	if q.stats {
+		q.count(q.metrics.gets, 1)
+	}
+	if q.emptyCallback != nil && q.emptyStreak.Load() != 0 {
+		q.emptyStreak.Store(0)
+	}
+	if !q.rate {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := q.lastGet.Swap(now)
+	if last == 0 {
+		return
+	}
+	// EWMA，新的间隔占 1/8，并发时可能丢掉个别更新，只是估算不影响
+	d := now - last
+	old := q.getInterval.Load()
+	if old == 0 {
+		q.getInterval.Store(d)
+		return
+	}
+	q.getInterval.Store(old + (d-old)/8)
+}
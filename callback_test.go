@@ -0,0 +1,193 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFullCallback(t *testing.T) {
+	var fired []uint32
+	q := newDefaultQueue(8, WithFullCallback(func(count uint32) {
+		fired = append(fired, count)
+	}))
+	q.PutSlice(ints(6))
+
+	for i := uint32(0); i < FullStreak-1; i++ {
+		q.Put(i)
+	}
+	if len(fired) != 0 {
+		t.Fatalf("fired before %d full puts", FullStreak)
+	}
+	q.Put(0)
+	if len(fired) != 1 || fired[0] != 6 {
+		t.Fatalf("fired %v after %d full puts, want [6]", fired, FullStreak)
+	}
+	// 同一段持续满只触发一次
+	for i := uint32(0); i < 3*FullStreak; i++ {
+		q.Put(i)
+	}
+	if len(fired) != 1 {
+		t.Fatalf("fired %d times during one full streak", len(fired))
+	}
+
+	// 放入成功后重新计数
+	q.Get()
+	q.Put(0)
+	for i := uint32(0); i < FullStreak; i++ {
+		q.Put(i)
+	}
+	if len(fired) != 2 {
+		t.Fatalf("fired %d times after a new full streak, want 2", len(fired))
+	}
+}
+
+func TestLatencyHookSampling(t *testing.T) {
+	counts := map[string]int{}
+	q := newDefaultQueue(8, WithLatencyHook(func(op string, d time.Duration) {
+		if d < 0 {
+			t.Errorf("negative duration %v", d)
+		}
+		counts[op]++
+	}), WithLatencySampling(4))
+
+	for i := 0; i < 100; i++ {
+		q.Put(i)
+		q.Get()
+	}
+	// Put 和 Get 共用一个计数器，每 4 次操作采样一次
+	if total := counts["put"] + counts["get"]; total != 50 {
+		t.Fatalf("sampled %d of 200 operations, want 50: %v", total, counts)
+	}
+}
+
+func TestLatencyHookAll(t *testing.T) {
+	counts := map[string]int{}
+	q := newDefaultQueue(8, WithLatencyHook(func(op string, d time.Duration) {
+		counts[op]++
+	}))
+	for i := 0; i < 10; i++ {
+		q.Put(i)
+	}
+	for i := 0; i < 10; i++ {
+		q.Get()
+	}
+	// 失败的也计时
+	if counts["put"] != 10 || counts["get"] != 10 {
+		t.Fatalf("sampled %v, want 10 of each", counts)
+	}
+}
+
+func TestPutCallback(t *testing.T) {
+	q := newDefaultQueue(8)
+	var order []int
+	calls := make([]int, 5)
+	for i := 0; i < 5; i++ {
+		i := i
+		q.PutCallback(i, func() {
+			calls[i]++
+			order = append(order, i)
+		})
+	}
+	if len(order) != 0 {
+		t.Fatal("callback called before consumed")
+	}
+
+	for i := 0; i < 5; i++ {
+		val, ok, _ := q.Get()
+		if !ok || val != i {
+			t.Fatalf("got %v, want %d", val, i)
+		}
+		// 取出后回调已经执行
+		if calls[i] != 1 {
+			t.Fatalf("callback of %d called %d times right after Get", i, calls[i])
+		}
+	}
+	// 槽被复用后不会再调用
+	q.PutSlice(ints(6))
+	drainAll(t, q)
+	for i, n := range calls {
+		if n != 1 {
+			t.Fatalf("callback of %d called %d times", i, n)
+		}
+	}
+	for i, v := range order {
+		if v != i {
+			t.Fatalf("callbacks in order %v, want FIFO", order)
+		}
+	}
+}
+
+func TestSpaceAvailableOncePerEdge(t *testing.T) {
+	fired := 0
+	q := newDefaultQueue(8, WithSpaceAvailable(func() { fired++ }))
+
+	// 没满过，取数据不触发
+	q.PutSlice(ints(3))
+	q.Get()
+	if fired != 0 {
+		t.Fatalf("fired %d times before full", fired)
+	}
+
+	for round := 1; round <= 3; round++ {
+		q.PutSlice(ints(8))
+		if q.Count() != 6 {
+			t.Fatalf("round %d: count %d, not full", round, q.Count())
+		}
+		q.Put(-1) // 满了之后再放也不会重复记录
+		q.Get()
+		if fired != round {
+			t.Fatalf("round %d: fired %d times on the full to not-full edge", round, fired)
+		}
+		// 同一次边沿之后继续取，不再触发
+		q.Get()
+		q.Get()
+		if fired != round {
+			t.Fatalf("round %d: fired %d times after more gets", round, fired)
+		}
+	}
+}
+
+func TestSpaceAvailableBatchGet(t *testing.T) {
+	fired := 0
+	q := newDefaultQueue(8, WithSpaceAvailable(func() { fired++ }))
+	q.PutSlice(ints(6))
+	buf := make([]interface{}, 4)
+	q.Gets(buf)
+	q.Gets(buf)
+	if fired != 1 {
+		t.Fatalf("fired %d times after batch gets from full", fired)
+	}
+}
+
+func TestEmptyCallbackOncePerStreak(t *testing.T) {
+	fired := 0
+	q := newDefaultQueue(8, WithEmptyCallback(func() { fired++ }))
+
+	for i := uint32(0); i < EmptyStreak-1; i++ {
+		q.Get()
+	}
+	if fired != 0 {
+		t.Fatalf("fired before %d empty gets", EmptyStreak)
+	}
+	q.Get()
+	if fired != 1 {
+		t.Fatalf("fired %d times after %d empty gets, want 1", fired, EmptyStreak)
+	}
+	// 同一段持续空只触发一次
+	for i := uint32(0); i < 3*EmptyStreak; i++ {
+		q.Get()
+	}
+	if fired != 1 {
+		t.Fatalf("fired %d times during one empty streak", fired)
+	}
+
+	// 取到数据后重新计数
+	q.Put(1)
+	q.Get()
+	for i := uint32(0); i < EmptyStreak; i++ {
+		q.Get()
+	}
+	if fired != 2 {
+		t.Fatalf("fired %d times after a new empty streak, want 2", fired)
+	}
+}
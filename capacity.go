@@ -0,0 +1,96 @@
+package queue
+
+/*
+ @File : capacity.go
+ @Description: reserve capacity ahead for a later sequence of puts
+ @Time : 2026/10/15
+*/
+
+import "sync"
+
+// Reservation n items of space held by ReserveCapacity, only its own Put can use them
+type Reservation struct {
+	q    *DefaultQueue
+	mu   sync.Mutex
+	left uint32 // 还没用掉的预留空间
+}
+
+// ReserveCapacity hold n items of space for the following n Reservation.Put,
+// Put and the other producers can't use the reserved space, they see the queue full earlier.
+// Each Reservation.Put turns one reserved space into an item in queue, call Release to give back the unused.
+// ok false if there isn't n free space now or closed.
+//
+// The reservation is NOT exact: a Put which checked the free space before ReserveCapacity
+// and takes its position after may still use the last free slot, then a Reservation.Put fails for full.
+// Once the Puts started before ReserveCapacity have finished, the reserved space can't be taken by others
+func (q *DefaultQueue) ReserveCapacity(n int) (r *Reservation, ok bool) {
+	if n <= 0 || n > int(q.capMod-1) || q.closed.Load() {
+		return nil, false
+	}
+	m := uint32(n)
+	for {
+		reserved := q.reserved.Load()
+		if q.Count()+reserved+m > q.capMod-1 {
+			return nil, false
+		}
+		if q.reserved.CAS(reserved, reserved+m) {
+			break
+		}
+	}
+	return &Reservation{q: q, left: m}, true
+}
+
+// Put put val into the reserved space, ignore the reservations when checking full,
+// fail when the reservation is used up or released, the queue is closed,
+// or the queue is full because a racing Put took the space, see ReserveCapacity
+func (r *Reservation) Put(val interface{}) (ok bool, count uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	q := r.q
+	for {
+		read := q.read.Load()
+		write := q.write.Load()
+
+		cnt := q.posCount(read, write)
+		if r.left == 0 || q.closed.Load() || cnt >= q.capMod-1 {
+			return false, cnt
+		}
+		posNext := write + 1
+		if !q.casWrite(write, posNext) {
+			q.yield() // 空间是预留好的，占位失败只是和其他生产者冲突，重试
+			continue
+		}
+		q.track(posNext, "")
+		q.putAt(posNext, entry{value: val})
+		// 先放入再归还，放入后这个空间已经算在 count 里
+		r.left--
+		q.reserved.Dec()
+		q.onPut(1)
+		return true, cnt + 1
+	}
+}
+
+// Release give back the reserved space not used by Put, the Reservation can't Put after that.
+// Safe to call more than once
+func (r *Reservation) Release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.q.reserved.Sub(r.left)
+	r.left = 0
+}
+
+// Remaining how many Put the reservation still allows
+func (r *Reservation) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int(r.left)
+}
+
+// usable 普通 Put 能用到的最大数量，最多 capMod-1 个，再减去预留的空间
+func (q *DefaultQueue) usable() uint32 {
+	reserved := q.reserved.Load()
+	if reserved >= q.capMod-1 {
+		return 0
+	}
+	return q.capMod - 1 - reserved
+}
@@ -0,0 +1,157 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestReserveCapacityShrinksOthers(t *testing.T) {
+	q := newDefaultQueue(16) // 最多 14 个
+	r, ok := q.ReserveCapacity(4)
+	if !ok {
+		t.Fatal("reserve 4 of 14 failed")
+	}
+	// 其他生产者只能用到 10 个
+	if n := q.PutSlice(ints(20)); n != 10 {
+		t.Fatalf("unreserved producers put %d, want 10", n)
+	}
+	if _, ok := q.ReserveCapacity(1); ok {
+		t.Fatal("reserved beyond the free space")
+	}
+	for i := 0; i < 4; i++ {
+		if ok, _ := r.Put(100 + i); !ok {
+			t.Fatalf("reserved put %d failed", i)
+		}
+	}
+	// 配额用完后不能再放
+	if ok, _ := r.Put(-1); ok || r.Remaining() != 0 {
+		t.Fatalf("put beyond the reservation succeeded, remaining %d", r.Remaining())
+	}
+	// 用掉的空间已经归还，只算作队列里的数据
+	if q.reserved.Load() != 0 || q.Count() != 14 {
+		t.Fatalf("reserved %d count %d after using up", q.reserved.Load(), q.Count())
+	}
+	vals := drainAll(t, q)
+	assertSeq(t, vals[:10], 0, 10)
+	for i, v := range vals[10:] {
+		if v != 100+i {
+			t.Fatalf("reserved item %d is %v", i, v)
+		}
+	}
+	if n := q.PutSlice(ints(20)); n != 14 {
+		t.Fatalf("put %d after the reservation is used up, want 14", n)
+	}
+}
+
+func TestReservationRelease(t *testing.T) {
+	q := newDefaultQueue(16)
+	r, _ := q.ReserveCapacity(6)
+	r.Put(1)
+	r.Put(2)
+	if r.Remaining() != 4 || q.reserved.Load() != 4 {
+		t.Fatalf("remaining %d reserved %d after 2 puts", r.Remaining(), q.reserved.Load())
+	}
+	r.Release()
+	r.Release() // 多次调用没有影响
+	if q.reserved.Load() != 0 || r.Remaining() != 0 {
+		t.Fatalf("reserved %d remaining %d after release", q.reserved.Load(), r.Remaining())
+	}
+	if ok, _ := r.Put(3); ok {
+		t.Fatal("put after release succeeded")
+	}
+	if n := q.PutSlice(ints(20)); n != 12 {
+		t.Fatalf("put %d after release, want 12", n)
+	}
+}
+
+func TestReserveCapacityInvalid(t *testing.T) {
+	q := newDefaultQueue(16)
+	for _, n := range []int{0, -1, 15} {
+		if _, ok := q.ReserveCapacity(n); ok {
+			t.Fatalf("reserved %d", n)
+		}
+	}
+	r, _ := q.ReserveCapacity(2)
+	q.Close()
+	if _, ok := q.ReserveCapacity(1); ok {
+		t.Fatal("reserved after close")
+	}
+	if ok, _ := r.Put(1); ok {
+		t.Fatal("reserved put after close succeeded")
+	}
+}
+
+func TestReservedPutsUnderContention(t *testing.T) {
+	q := newDefaultQueue(64)
+	for round := 0; round < 50; round++ {
+		r, ok := q.ReserveCapacity(20)
+		if !ok {
+			t.Fatalf("round %d: reserve failed, count %d", round, q.Count())
+		}
+		// 其他生产者一直往里放，预留的空间不受影响
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for p := 0; p < 3; p++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						q.Put(-1)
+					}
+				}
+			}()
+		}
+		for i := 0; i < 20; i++ {
+			if ok, _ := r.Put(i); !ok {
+				t.Fatalf("round %d: reserved put %d failed", round, i)
+			}
+		}
+		close(stop)
+		wg.Wait()
+		if q.reserved.Load() != 0 {
+			t.Fatalf("round %d: reserved %d left", round, q.reserved.Load())
+		}
+		got := 0
+		for _, v := range drainAll(t, q) {
+			if v != -1 {
+				got++
+			}
+		}
+		if got != 20 {
+			t.Fatalf("round %d: %d reserved items in queue", round, got)
+		}
+	}
+}
+
+func TestReservationPutRacedFull(t *testing.T) {
+	q := newDefaultQueue(8) // 最多 6 个
+	q.PutSlice(ints(5))
+
+	// 一个普通的 Put 在预留之前检查了空间，预留之后才占位置，用掉了最后一个空位
+	write := q.write.Load()
+	if cnt := q.posCount(q.read.Load(), write); cnt >= q.usable() {
+		t.Fatalf("count %d, want room for one more", cnt)
+	}
+	r, ok := q.ReserveCapacity(1)
+	if !ok {
+		t.Fatal("reserve failed")
+	}
+	if !q.casWrite(write, write+1) {
+		t.Fatal("lock the slot failed")
+	}
+	q.putAt(write+1, entry{value: -1})
+	q.onPut(1)
+
+	// 预留不是精确的，这时 Reservation.Put 因为满而失败
+	if ok, _ := r.Put(0); ok {
+		t.Fatal("reserved put succeeded on a full queue")
+	}
+	r.Release()
+	if q.reserved.Load() != 0 {
+		t.Fatalf("reserved %d left after release", q.reserved.Load())
+	}
+}
@@ -0,0 +1,34 @@
+package queue
+
+/*
+ @File : compare.go
+ @Description: conditional put, only when the count is the expected one
+ @Time : 2026/10/15
+*/
+
+// CompareAndPut put val only if the count is expectedCount when the position is locked,
+// for example CompareAndPut(0, val) put only into an empty queue, for the leader/singleton patterns.
+// The CAS on write makes sure no other producer put in between, but consumers may still take items,
+// so the count can be less than expectedCount at the moment val is put.
+// Fail if the count doesn't match, or closed, or full, or lock slot failed
+func (q *DefaultQueue) CompareAndPut(expectedCount uint32, val interface{}) (ok bool) {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := q.posCount(read, write)
+	if cnt != expectedCount || q.closed.Load() {
+		return false
+	}
+	if cnt >= q.usable() || (q.spill != nil && q.spill.len() > 0) {
+		q.onFull(cnt)
+		return false
+	}
+	posNext := write + 1
+	if !q.casWrite(write, posNext) {
+		q.yield()
+		return false
+	}
+	q.putAt(posNext, entry{value: val})
+	q.onPut(1)
+	return true
+}
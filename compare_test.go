@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCompareAndPut(t *testing.T) {
+	q := newDefaultQueue(8)
+	if !q.CompareAndPut(0, "first") {
+		t.Fatal("put into empty queue with expected 0 failed")
+	}
+	// 数量不匹配时失败，也不放入
+	if q.CompareAndPut(0, "second") || q.CompareAndPut(2, "second") {
+		t.Fatal("put with a mismatching count succeeded")
+	}
+	if q.Count() != 1 {
+		t.Fatalf("count %d after mismatches", q.Count())
+	}
+	if !q.CompareAndPut(1, "second") {
+		t.Fatal("put with matching count 1 failed")
+	}
+	vals := drainAll(t, q)
+	if len(vals) != 2 || vals[0] != "first" || vals[1] != "second" {
+		t.Fatalf("queue holds %v", vals)
+	}
+
+	// 满了即使数量匹配也失败
+	n := q.PutSlice(ints(8))
+	if q.CompareAndPut(uint32(n), -1) {
+		t.Fatal("put into a full queue succeeded")
+	}
+	drainAll(t, q)
+	q.Close()
+	if q.CompareAndPut(0, -1) {
+		t.Fatal("put after close succeeded")
+	}
+}
+
+func TestCompareAndPutSingleton(t *testing.T) {
+	// 很多生产者同时 "只在空的时候放入"，只有一个能成功
+	for round := 0; round < 100; round++ {
+		q := newDefaultQueue(8)
+		var mu sync.Mutex
+		winners := 0
+		var wg sync.WaitGroup
+		for p := 0; p < 8; p++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if q.CompareAndPut(0, "leader") {
+					mu.Lock()
+					winners++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if winners > 1 || q.Count() != uint32(winners) {
+			t.Fatalf("round %d: %d winners, count %d", round, winners, q.Count())
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package queue
+
+/*
+ @File : consume.go
+ @Description: packaged produce/consume loops
+ @Time : 2026/10/15
+*/
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ConsumerGroup start workers goroutines, each one Get items and call fn with backoff when empty,
+// every item is passed to fn only once. Block until all workers stop,
+// they stop when ctx is done, or the queue is closed and all items are consumed
+func (q *DefaultQueue) ConsumerGroup(ctx context.Context, workers int, fn func(val interface{})) {
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.consumeLoop(ctx, fn)
+		}()
+	}
+	wg.Wait()
+}
+
+// consumeLoop 一直取数据交给 fn，直到 ctx 结束，或者队列关闭并且取完
+func (q *DefaultQueue) consumeLoop(ctx context.Context, fn func(val interface{})) {
+	var i uint32
+	for ctx.Err() == nil {
+		val, ok, cnt := q.Get()
+		if ok {
+			fn(val)
+			i = 0
+			continue
+		}
+		if cnt == 0 && q.closed.Load() {
+			return
+		}
+		backoff(i)
+		i++
+	}
+}
+
+// Produce put the items from gen one by one until gen return ok false,
+// wait with backoff when full, return nil when gen is exhausted,
+// or the error of PutContext when closed or ctx is done
+func (q *DefaultQueue) Produce(ctx context.Context, gen func() (val interface{}, ok bool)) error {
+	for {
+		val, ok := gen()
+		if !ok {
+			return nil
+		}
+		if err := q.PutContext(ctx, val); err != nil {
+			return err
+		}
+	}
+}
+
+// ConsumeErr the same as ConsumerGroup but fn may return error, run in background and return at once.
+// The errors of fn are sent to the returned channel, the loops go on unless stopOnErr,
+// in which case all workers stop after the first error.
+// The channel is closed after all workers stop, the caller must keep receiving until then,
+// or the workers block on sending until ctx is done
+func (q *DefaultQueue) ConsumeErr(ctx context.Context, workers int, fn func(val interface{}) error, stopOnErr bool) <-chan error {
+	ctx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.consumeLoop(ctx, func(val interface{}) {
+				if err := fn(val); err != nil {
+					sendErr(ctx, errCh, err)
+					if stopOnErr {
+						cancel()
+					}
+				}
+			})
+		}()
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(errCh)
+	}()
+	return errCh
+}
+
+// ProduceErr the same as Produce but gen may return error, run in background and return at once.
+// The item is skipped when gen return error, the error is sent to the returned channel,
+// and the loop goes on unless stopOnErr. The error of PutContext is also sent and always stop the loop.
+// The channel is closed after the loop stops, the caller must keep receiving until then
+func (q *DefaultQueue) ProduceErr(ctx context.Context, gen func() (val interface{}, ok bool, err error), stopOnErr bool) <-chan error {
+	errCh := make(chan error)
+	go func() {
+		defer close(errCh)
+		for {
+			val, ok, err := gen()
+			if err != nil {
+				sendErr(ctx, errCh, err)
+				if stopOnErr {
+					return
+				}
+				continue
+			}
+			if !ok {
+				return
+			}
+			if err := q.PutContext(ctx, val); err != nil {
+				sendErr(ctx, errCh, err)
+				return
+			}
+		}
+	}()
+	return errCh
+}
+
+// sendErr 把错误交给调用方，ctx 结束后调用方可能已经不再接收，这时丢弃
+func sendErr(ctx context.Context, errCh chan<- error, err error) {
+	select {
+	case errCh <- err:
+	case <-ctx.Done():
+	}
+}
+
+// ConsumeBatched get items by Gets and call fn with micro-batches, a batch is flushed when it has maxItems,
+// or maxWait passed since its first item, the empty windows are skipped.
+// The batch slice is reused after fn returns, copy it if needed.
+// Block until ctx is done, or the queue is closed and all items are consumed, the last batch is flushed before return
+func (q *DefaultQueue) ConsumeBatched(ctx context.Context, maxItems int, maxWait time.Duration, fn func([]interface{})) {
+	q.consumeBatched(ctx, maxItems, maxWait, false, fn)
+}
+
+// ConsumeBatchedTick the same as ConsumeBatched, but every window is flushed after maxWait even if it's empty,
+// fn is called with an empty slice then, for the consumers which need a periodic tick
+func (q *DefaultQueue) ConsumeBatchedTick(ctx context.Context, maxItems int, maxWait time.Duration, fn func([]interface{})) {
+	q.consumeBatched(ctx, maxItems, maxWait, true, fn)
+}
+
+// consumeBatched callEmpty 为 false 时，窗口从取到第一个数据开始计时
+func (q *DefaultQueue) consumeBatched(ctx context.Context, maxItems int, maxWait time.Duration, callEmpty bool, fn func([]interface{})) {
+	if maxItems < 1 {
+		maxItems = 1
+	}
+	buf := make([]interface{}, maxItems)
+	filled := 0
+	deadline := time.Now().Add(maxWait)
+	flush := func() {
+		if filled > 0 || callEmpty {
+			fn(buf[:filled])
+		}
+		for i := 0; i < filled; i++ {
+			buf[i] = nil
+		}
+		filled = 0
+		deadline = time.Now().Add(maxWait)
+	}
+
+	var i uint32
+	for ctx.Err() == nil {
+		n, cnt := q.Gets(buf[filled:])
+		if n > 0 && filled == 0 && !callEmpty {
+			deadline = time.Now().Add(maxWait)
+		}
+		filled += int(n)
+		if filled == maxItems || !time.Now().Before(deadline) {
+			flush()
+			i = 0
+			continue
+		}
+		if n > 0 {
+			i = 0
+			continue
+		}
+		if cnt == 0 && q.closed.Load() {
+			break
+		}
+		backoff(i)
+		i++
+	}
+	if filled > 0 {
+		flush()
+	}
+}
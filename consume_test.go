@@ -0,0 +1,223 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConsumerGroupExactlyOnce(t *testing.T) {
+	q := newDefaultQueue(64)
+	const n = 5000
+	go func() {
+		for i := 0; i < n; {
+			if ok, _ := q.Put(i); ok {
+				i++
+			}
+		}
+		q.Close()
+	}()
+
+	var mu sync.Mutex
+	seen := make([]int, n)
+	// 队列关闭并且取完后所有 worker 退出
+	q.ConsumerGroup(context.Background(), 8, func(val interface{}) {
+		mu.Lock()
+		seen[val.(int)]++
+		mu.Unlock()
+	})
+	for v, c := range seen {
+		if c != 1 {
+			t.Fatalf("item %d consumed %d times", v, c)
+		}
+	}
+}
+
+func TestConsumerGroupCancel(t *testing.T) {
+	q := newDefaultQueue(64)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.ConsumerGroup(ctx, 4, func(interface{}) {})
+		close(done)
+	}()
+	time.Sleep(time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("workers not stopped after cancel")
+	}
+}
+
+func TestConsumeErrSurfacesErrors(t *testing.T) {
+	q := newDefaultQueue(64)
+	q.PutSlice(ints(50))
+	q.Close()
+
+	var mu sync.Mutex
+	handled := 0
+	errCh := q.ConsumeErr(context.Background(), 3, func(val interface{}) error {
+		mu.Lock()
+		handled++
+		mu.Unlock()
+		if val.(int)%10 == 0 {
+			return fmt.Errorf("bad %d", val)
+		}
+		return nil
+	}, false)
+
+	var errs []error
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	// 出错不影响后面的数据，所有数据都处理了
+	if handled != 50 || len(errs) != 5 {
+		t.Fatalf("handled %d with %d errors, want 50 and 5", handled, len(errs))
+	}
+}
+
+func TestConsumeErrStopOnErr(t *testing.T) {
+	q := newDefaultQueue(64)
+	q.PutSlice(ints(50))
+
+	errCh := q.ConsumeErr(context.Background(), 1, func(val interface{}) error {
+		if val == 3 {
+			return errors.New("stop")
+		}
+		return nil
+	}, true)
+	var errs []error
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	// 第一个错误后停止，没有关闭的队列也不会一直等
+	if len(errs) != 1 || errs[0].Error() != "stop" {
+		t.Fatalf("errors %v", errs)
+	}
+	if q.Count() != 46 {
+		t.Fatalf("count %d after stopping at item 3, want 46", q.Count())
+	}
+}
+
+func TestProduceErrSurfacesErrors(t *testing.T) {
+	q := newDefaultQueue(64)
+	i := 0
+	gen := func() (interface{}, bool, error) {
+		i++
+		if i > 20 {
+			return nil, false, nil
+		}
+		if i%5 == 0 {
+			return nil, true, fmt.Errorf("gen %d", i)
+		}
+		return i, true, nil
+	}
+	var errs []error
+	for err := range q.ProduceErr(context.Background(), gen, false) {
+		errs = append(errs, err)
+	}
+	// 出错的跳过，其余的都放入
+	if len(errs) != 4 || q.Count() != 16 {
+		t.Fatalf("%d errors, count %d, want 4 and 16", len(errs), q.Count())
+	}
+}
+
+func TestProduceErrStopAndPutError(t *testing.T) {
+	q := newDefaultQueue(64)
+	i := 0
+	gen := func() (interface{}, bool, error) {
+		i++
+		if i == 3 {
+			return nil, true, errors.New("gen failed")
+		}
+		return i, true, nil
+	}
+	var errs []error
+	for err := range q.ProduceErr(context.Background(), gen, true) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || q.Count() != 2 {
+		t.Fatalf("errors %v, count %d, want 1 error and 2 items", errs, q.Count())
+	}
+
+	// PutContext 的错误也发出来，并且停止
+	q.Close()
+	errs = errs[:0]
+	for err := range q.ProduceErr(context.Background(), func() (interface{}, bool, error) { return 1, true, nil }, false) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] != ErrClosed {
+		t.Fatalf("errors %v after close, want [ErrClosed]", errs)
+	}
+}
+
+func TestConsumeBatchedFlushOnCount(t *testing.T) {
+	q := newDefaultQueue(64)
+	q.PutSlice(ints(25))
+	q.Close()
+
+	var sizes []int
+	var got []interface{}
+	// 窗口很长，只会因为数量满了而提交，最后剩下的在退出前提交
+	q.ConsumeBatched(context.Background(), 10, time.Hour, func(batch []interface{}) {
+		sizes = append(sizes, len(batch))
+		got = append(got, batch...)
+	})
+	if len(sizes) != 3 || sizes[0] != 10 || sizes[1] != 10 || sizes[2] != 5 {
+		t.Fatalf("batch sizes %v, want [10 10 5]", sizes)
+	}
+	assertSeq(t, got, 0, 25)
+}
+
+func TestConsumeBatchedFlushOnTime(t *testing.T) {
+	q := newDefaultQueue(64)
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan []interface{}, 10)
+	go func() {
+		q.ConsumeBatched(ctx, 100, 20*time.Millisecond, func(batch []interface{}) {
+			batches <- append([]interface{}(nil), batch...) // batch 会被复用，复制一份
+		})
+		close(batches)
+	}()
+	defer cancel()
+
+	q.PutSlice(ints(3))
+	start := time.Now()
+	select {
+	case batch := <-batches:
+		// 没有满，等到窗口结束才提交
+		if d := time.Since(start); d < 15*time.Millisecond {
+			t.Fatalf("flushed after %v, before the window", d)
+		}
+		assertSeq(t, batch, 0, 3)
+	case <-time.After(5 * time.Second):
+		t.Fatal("partial batch not flushed after maxWait")
+	}
+
+	// 空的窗口跳过，不调用 fn
+	select {
+	case batch := <-batches:
+		t.Fatalf("flushed %v for an empty window", batch)
+	case <-time.After(60 * time.Millisecond):
+	}
+}
+
+func TestConsumeBatchedTickEmptyWindows(t *testing.T) {
+	q := newDefaultQueue(64)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	empty := 0
+	q.ConsumeBatchedTick(ctx, 10, 10*time.Millisecond, func(batch []interface{}) {
+		if len(batch) == 0 {
+			empty++
+		}
+	})
+	// 没有数据时每个窗口也调用一次
+	if empty < 3 {
+		t.Fatalf("%d empty ticks in 100ms with a 10ms window", empty)
+	}
+}
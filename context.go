@@ -0,0 +1,182 @@
+package queue
+
+/*
+ @File : context.go
+ @Description: put/get which wait until success or the context is done, and return sentinel errors
+ @Time : 2026/10/15
+*/
+
+import (
+	"context"
+	"time"
+)
+
+// TryPut put val without waiting for room, retry only when lock slot failed,
+// return ErrClosed if closed, ErrFull if full,
+// or the error of writing to disk with WithSpillover, such as the type not registered to gob
+func (q *DefaultQueue) TryPut(val interface{}) error {
+	for {
+		var err error
+		if q.latencyHook != nil && q.sampleLatency() {
+			start := time.Now()
+			_, _, err = q.putPosErr(entry{value: val})
+			q.latencyHook("put", time.Since(start))
+		} else {
+			_, _, err = q.putPosErr(entry{value: val})
+		}
+		if err != errConflict {
+			return err
+		}
+	}
+}
+
+// PutContext put val and wait with backoff until there is room or ctx is done,
+// return ErrClosed if closed, ErrTimeout if the deadline of ctx exceeded, ctx.Err() if canceled
+func (q *DefaultQueue) PutContext(ctx context.Context, val interface{}) error {
+	for i := uint32(0); ; i++ {
+		if q.closed.Load() {
+			return ErrClosed
+		}
+		if ok, _ := q.Put(val); ok {
+			return nil
+		}
+		if err := ctxErr(ctx); err != nil {
+			return err
+		}
+		backoff(i)
+	}
+}
+
+// GetContext get an item and wait with backoff until there is one or ctx is done,
+// return ErrClosed if closed and empty, ErrTimeout if the deadline of ctx exceeded, ctx.Err() if canceled
+func (q *DefaultQueue) GetContext(ctx context.Context) (interface{}, error) {
+	for i := uint32(0); ; i++ {
+		val, ok, cnt := q.Get()
+		if ok {
+			return val, nil
+		}
+		if cnt == 0 && q.closed.Load() {
+			return nil, ErrClosed
+		}
+		if err := ctxErr(ctx); err != nil {
+			return nil, err
+		}
+		backoff(i)
+	}
+}
+
+// WaitForCount wait with backoff until the count is at least target (atLeast true), for triggering a batch,
+// or at most target (atLeast false), for waiting a drain, return nil once reached.
+// Return ErrClosed if waiting for more items on a closed queue,
+// ErrTimeout if the deadline of ctx exceeded, ctx.Err() if canceled
+func (q *DefaultQueue) WaitForCount(ctx context.Context, target uint32, atLeast bool) error {
+	for i := uint32(0); ; i++ {
+		cnt := q.Count()
+		if (atLeast && cnt >= target) || (!atLeast && cnt <= target) {
+			return nil
+		}
+		if atLeast && q.closed.Load() {
+			return ErrClosed
+		}
+		if err := ctxErr(ctx); err != nil {
+			return err
+		}
+		// 最多等 1ms，数量的变化不会被错过太久
+		backoff(i)
+	}
+}
+
+// PutAllChunked put all vals in order, as many as there is room each time, and wait with backoff
+// for consumers to make room for the rest, for a batch much bigger than the queue.
+// Return how many put, vals[:n] are in queue, with ErrClosed if closed, or the error of ctx as PutContext
+func (q *DefaultQueue) PutAllChunked(ctx context.Context, vals []interface{}) (int, error) {
+	put := 0
+	for i := uint32(0); put < len(vals); {
+		if q.closed.Load() {
+			return put, ErrClosed
+		}
+		n, _ := q.Puts(vals[put:])
+		put += int(n)
+		if n > 0 {
+			i = 0
+			continue
+		}
+		if err := ctxErr(ctx); err != nil {
+			return put, err
+		}
+		backoff(i)
+		i++
+	}
+	return put, nil
+}
+
+// PutCtx the same as Put, never block, and carry the values of ctx (trace span, baggage, etc.) with the item,
+// the consumer restore them by GetCtx. Only the values are passed, not the deadline or cancellation of ctx.
+// Always fail if the item would be written to disk by WithSpillover, see PutCallback
+func (q *DefaultQueue) PutCtx(ctx context.Context, val interface{}) (ok bool, count uint32) {
+	return q.put(entry{value: val, x: &extra{ctx: valueOnly{ctx}}})
+}
+
+// GetCtx the same as Get, and return a context with the values carried by PutCtx,
+// context.Background() if the item was not put by PutCtx
+func (q *DefaultQueue) GetCtx() (ctx context.Context, val interface{}, ok bool, count uint32) {
+	e, ok, count := q.getEntry()
+	ctx = context.Background()
+	if e.x != nil && e.x.ctx != nil {
+		ctx = e.x.ctx
+	}
+	return ctx, e.value, ok, count
+}
+
+// valueOnly 只保留 context 中的值，去掉超时和取消，生产者的 context 结束后消费者仍然可以使用
+type valueOnly struct {
+	parent context.Context
+}
+
+func (valueOnly) Deadline() (deadline time.Time, ok bool) { return }
+func (valueOnly) Done() <-chan struct{}                   { return nil }
+func (valueOnly) Err() error                              { return nil }
+func (c valueOnly) Value(key interface{}) interface{}     { return c.parent.Value(key) }
+
+// ctxErr 超时转换为 ErrTimeout，其他原因原样返回
+func ctxErr(ctx context.Context) error {
+	err := ctx.Err()
+	if err == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return err
+}
+
+// PutAndWait put val and block until a consumer takes it out, or ctx is done, for request-response over the queue.
+// Wait with backoff when full like PutContext, return the same errors,
+// the item stays in queue if ctx is done after it was put.
+// With WithSpillover it fails with ErrFull at once when full, instead of waiting,
+// as the item would be written to disk, see PutCallback
+func (q *DefaultQueue) PutAndWait(ctx context.Context, val interface{}) error {
+	consumed := make(chan struct{})
+	e := entry{value: val, x: &extra{done: func() { close(consumed) }}}
+	for i := uint32(0); ; i++ {
+		if q.closed.Load() {
+			return ErrClosed
+		}
+		_, _, err := q.putPosErr(e)
+		if err == nil {
+			break
+		}
+		// 开启溢出后满了就要写到磁盘，带回调的数据写不了，只要满了就失败，不管磁盘上有没有数据
+		if err == errNotPlain {
+			return ErrFull
+		}
+		if err := ctxErr(ctx); err != nil {
+			return err
+		}
+		backoff(i)
+	}
+
+	select {
+	case <-consumed:
+		return nil
+	case <-ctx.Done():
+		return ctxErr(ctx)
+	}
+}
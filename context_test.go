@@ -0,0 +1,310 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestClosedErrors(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.Put(1)
+	if err := q.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := q.Close(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("double close: %v, want ErrClosed", err)
+	}
+	ctx := context.Background()
+	if err := q.PutContext(ctx, 2); !errors.Is(err, ErrClosed) {
+		t.Fatalf("PutContext: %v, want ErrClosed", err)
+	}
+	if err := q.TryPut(2); !errors.Is(err, ErrClosed) {
+		t.Fatalf("TryPut: %v, want ErrClosed", err)
+	}
+	// 关闭后剩下的数据仍然可以取，取完后返回 ErrClosed
+	if val, err := q.GetContext(ctx); val != 1 || err != nil {
+		t.Fatalf("GetContext: %v %v, want the remaining item", val, err)
+	}
+	if _, err := q.GetContext(ctx); !errors.Is(err, ErrClosed) {
+		t.Fatalf("GetContext on closed empty queue: %v, want ErrClosed", err)
+	}
+}
+
+func TestFullAndTimeoutErrors(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.PutSlice(ints(6))
+	if err := q.TryPut(6); !errors.Is(err, ErrFull) {
+		t.Fatalf("TryPut on full queue: %v, want ErrFull", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := q.PutContext(ctx, 6); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("PutContext on full queue: %v, want ErrTimeout", err)
+	}
+
+	empty := newDefaultQueue(8)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := empty.GetContext(ctx); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("GetContext on empty queue: %v, want ErrTimeout", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := q.PutContext(ctx, 6); !errors.Is(err, context.Canceled) {
+		t.Fatalf("PutContext canceled: %v, want context.Canceled", err)
+	}
+}
+
+type unregisteredValue struct{ A int }
+
+func TestTryPutSpillError(t *testing.T) {
+	q := newDefaultQueue(8, WithSpillover(t.TempDir()))
+	for i := 0; i < 7; i++ {
+		q.Put(i) // 最后一个写到磁盘
+	}
+	// 不经过 refill 取走一个，环形队列有空间，但磁盘上还有数据
+	q.DrainFunc(func(interface{}) bool { return false })
+	if q.Count() >= q.usable() || q.spill.len() != 1 {
+		t.Fatalf("count %d spilled %d, want room in ring and 1 on disk", q.Count(), q.spill.len())
+	}
+
+	// 写磁盘失败时返回错误，而不是一直重试
+	done := make(chan error, 1)
+	go func() { done <- q.TryPut(unregisteredValue{1}) }()
+	select {
+	case err := <-done:
+		if err == nil || errors.Is(err, ErrFull) || errors.Is(err, ErrClosed) {
+			t.Fatalf("TryPut: %v, want the gob error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("TryPut spins when writing to disk failed")
+	}
+	if err := q.TryPut(7); err != nil {
+		t.Fatalf("TryPut of a gob encodable value: %v", err)
+	}
+}
+
+func TestPutAndWaitUnblocksOnItsItem(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.Put("before")
+	done := make(chan error)
+	go func() { done <- q.PutAndWait(context.Background(), "mine") }()
+
+	// 等它放入
+	for q.Count() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("returned %v before any get", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// 取走前面的数据不会让它返回
+	q.Get()
+	q.Put("after")
+	select {
+	case err := <-done:
+		t.Fatalf("returned %v when another item was consumed", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if val, _, _ := q.Get(); val != "mine" {
+		t.Fatalf("got %v, want mine", val)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("returned %v after its item was consumed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("still blocked after its item was consumed")
+	}
+}
+
+func TestPutAndWaitCancel(t *testing.T) {
+	q := newDefaultQueue(8)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.PutAndWait(ctx, "v"); err != ErrTimeout {
+		t.Fatalf("err %v when nobody consumes", err)
+	}
+	// ctx 结束后数据仍然留在队列里
+	if val, ok, _ := q.Get(); !ok || val != "v" {
+		t.Fatalf("item not left in queue: %v %v", val, ok)
+	}
+
+	q.Close()
+	if err := q.PutAndWait(context.Background(), "x"); err != ErrClosed {
+		t.Fatalf("err %v after close, want ErrClosed", err)
+	}
+}
+
+type traceKey struct{}
+
+func TestPutCtxPropagatesValues(t *testing.T) {
+	q := newDefaultQueue(8)
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), traceKey{}, "span-1"))
+	if ok, _ := q.PutCtx(ctx, 1); !ok {
+		t.Fatal("PutCtx failed")
+	}
+	q.Put(2)
+	// 生产者的 context 结束了，消费者拿到的值还在，也不会被取消
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		got, val, ok, _ := q.GetCtx()
+		if !ok || val != 1 {
+			t.Errorf("got %v %v, want 1", val, ok)
+		}
+		if got.Value(traceKey{}) != "span-1" {
+			t.Errorf("trace value %v, want span-1", got.Value(traceKey{}))
+		}
+		if got.Err() != nil || got.Done() != nil {
+			t.Errorf("cancellation of the producer carried over: %v", got.Err())
+		}
+
+		// 普通 Put 的没有值
+		got, val, ok, _ = q.GetCtx()
+		if !ok || val != 2 || got.Value(traceKey{}) != nil {
+			t.Errorf("got %v %v with trace %v, want 2 with none", val, ok, got.Value(traceKey{}))
+		}
+	}()
+	<-done
+}
+
+func TestPutAllChunked(t *testing.T) {
+	q := newDefaultQueue(8)
+	const n = 500
+	got := make(chan []interface{})
+	go func() {
+		var vals []interface{}
+		for len(vals) < n {
+			if val, ok, _ := q.Get(); ok {
+				vals = append(vals, val)
+			}
+		}
+		got <- vals
+	}()
+
+	// 数据远多于队列容量，随着消费者取走分批放入
+	put, err := q.PutAllChunked(context.Background(), ints(n))
+	if err != nil || put != n {
+		t.Fatalf("put %d err %v, want all %d", put, err, n)
+	}
+	select {
+	case vals := <-got:
+		assertSeq(t, vals, 0, n)
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer didn't get all items")
+	}
+}
+
+func TestPutAllChunkedCancel(t *testing.T) {
+	q := newDefaultQueue(8)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// 没有消费者，放满之后等到超时
+	put, err := q.PutAllChunked(ctx, ints(20))
+	if !errors.Is(err, ErrTimeout) || put != 6 {
+		t.Fatalf("put %d err %v, want 6 and ErrTimeout", put, err)
+	}
+	assertSeq(t, drainAll(t, q), 0, 6)
+}
+
+func TestWaitForCountAtLeast(t *testing.T) {
+	q := newDefaultQueue(16)
+	go func() {
+		for i := 0; i < 5; i++ {
+			time.Sleep(time.Millisecond)
+			q.Put(i)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.WaitForCount(ctx, 5, true); err != nil {
+		t.Fatalf("wait for 5 items: %v", err)
+	}
+	if cnt := q.Count(); cnt < 5 {
+		t.Fatalf("returned with count %d", cnt)
+	}
+	// 已经满足时马上返回
+	if err := q.WaitForCount(ctx, 3, true); err != nil {
+		t.Fatalf("wait for 3 items: %v", err)
+	}
+}
+
+func TestWaitForCountAtMost(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(8))
+	go func() {
+		for i := 0; i < 8; i++ {
+			time.Sleep(time.Millisecond)
+			q.Get()
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.WaitForCount(ctx, 2, false); err != nil {
+		t.Fatalf("wait for at most 2 items: %v", err)
+	}
+	if cnt := q.Count(); cnt > 2 {
+		t.Fatalf("returned with count %d", cnt)
+	}
+}
+
+func TestWaitForCountCancel(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.Put(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.WaitForCount(ctx, 3, true); err != ErrTimeout {
+		t.Fatalf("wait for 3 items: err %v, want ErrTimeout", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := q.WaitForCount(ctx, 0, false); err != context.Canceled {
+		t.Fatalf("wait for empty: err %v, want context.Canceled", err)
+	}
+
+	// 关闭后等不到更多的数据
+	q.Close()
+	if err := q.WaitForCount(context.Background(), 3, true); err != ErrClosed {
+		t.Fatalf("wait on a closed queue: err %v, want ErrClosed", err)
+	}
+}
+
+func TestPutCtxKeepsSlotSmall(t *testing.T) {
+	// 附带信息都在单独分配的 extra 中，槽里只有数据和一个指针
+	var e entry
+	if size, want := unsafe.Sizeof(e), unsafe.Sizeof(e.value)+unsafe.Sizeof(e.x); size != want {
+		t.Fatalf("entry is %d bytes, want %d", size, want)
+	}
+	// 普通的 Put 不分配附带信息
+	q := newDefaultQueue(8)
+	q.Put(1)
+	q.PutCtx(context.Background(), 2)
+	if q.carrier[1].x != nil || q.carrier[2].x == nil {
+		t.Fatalf("extra %v %v, want nil for Put only", q.carrier[1].x, q.carrier[2].x)
+	}
+}
+
+func TestPutAndWaitSpillFull(t *testing.T) {
+	q := newDefaultQueue(8, WithSpillover(t.TempDir()))
+	for q.Count() < q.usable() {
+		q.Put(0)
+	}
+	// 环满了，磁盘上还没有数据，也要马上失败，而不是一直等
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.PutAndWait(ctx, "v"); err != ErrFull {
+		t.Fatalf("err %v on a full ring with spill, want ErrFull", err)
+	}
+}
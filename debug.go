@@ -0,0 +1,42 @@
+package queue
+
+/*
+ @File : debug.go
+ @Description: advanced and diagnostic api, expose the internal state of the commit protocol,
+               unstable, may change with the implementation
+ @Time : 2026/10/15
+*/
+
+// SlotVersion return the raw writeID and readID of the slot at index (masked by cap - 1).
+// The slot is empty when writeID == readID, and has a committed value when writeID == readID + cap,
+// both of them increase by cap on each round.
+// Advanced and unstable api, for the users extending the queue who need to reason about the protocol
+func (q *DefaultQueue) SlotVersion(index uint32) (writeID, readID uint32) {
+	cache := &q.carrier[index&q.capMod]
+	return cache.writeID.Load(), cache.readID.Load()
+}
+
+// GetIndexed the same as Get, and return the index of the slot which the value comes from,
+// the index goes through 0 ~ cap-1 round by round, for the users building indexes over the slots.
+// index is meaningless when ok is false
+func (q *DefaultQueue) GetIndexed() (val interface{}, index uint32, ok bool) {
+	e, pos, ok, _ := q.getEntryPos()
+	return e.value, pos & q.capMod, ok
+}
+
+// PutHandle the same as Put, and return the global write position assigned to the value as a handle,
+// for correlating the item with external logs, GetHandle return the same handle for it.
+// Unlike PutSeq it's the internal position, not a separate counter,
+// monotonic among the puts and unique until the 32 bit position wraps around.
+// handle is 0 when ok is false, or the value is spilled to disk (see WithSpillover)
+func (q *DefaultQueue) PutHandle(val interface{}) (handle uint64, ok bool) {
+	pos, ok, _ := q.putPos(entry{value: val})
+	return uint64(pos), ok
+}
+
+// GetHandle the same as Get, and return the handle which PutHandle returned for the value,
+// handle is meaningless when ok is false
+func (q *DefaultQueue) GetHandle() (val interface{}, handle uint64, ok bool) {
+	e, pos, ok, _ := q.getEntryPos()
+	return e.value, uint64(pos), ok
+}
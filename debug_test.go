@@ -0,0 +1,105 @@
+package queue
+
+import "testing"
+
+func TestSlotVersion(t *testing.T) {
+	q := newDefaultQueue(8)
+	const index = 3
+	w0, r0 := q.SlotVersion(index)
+	if w0 != r0 {
+		t.Fatalf("fresh slot writeID %d readID %d, want equal", w0, r0)
+	}
+	for round := uint32(1); round <= 3; round++ {
+		// 一轮 8 个位置，第 index 个用到这个槽
+		for i := 0; i < 8; i++ {
+			q.Put(i)
+			if i == index-1 {
+				w, r := q.SlotVersion(index)
+				if w != w0+(round-1)*q.cap+q.cap || r != r0+(round-1)*q.cap {
+					t.Fatalf("round %d after put: writeID %d readID %d", round, w, r)
+				}
+			}
+			q.Get()
+		}
+		w, r := q.SlotVersion(index)
+		if w != w0+round*q.cap || r != r0+round*q.cap {
+			t.Fatalf("round %d after get: writeID %d readID %d, want both +%d", round, w, r, round*q.cap)
+		}
+	}
+	// index 超过容量时按 cap 取余
+	w, r := q.SlotVersion(index + q.cap)
+	if w1, r1 := q.SlotVersion(index); w != w1 || r != r1 {
+		t.Fatal("index not masked by cap")
+	}
+}
+
+func TestGetIndexedCycles(t *testing.T) {
+	q := newDefaultQueue(8)
+	// 位置从 1 开始，第一个数据在 1 号槽，之后按顺序回绕
+	want := uint32(1)
+	for i := 0; i < 50; i++ {
+		q.Put(i)
+		val, index, ok := q.GetIndexed()
+		if !ok || val != i {
+			t.Fatalf("get %v %v, want %d", val, ok, i)
+		}
+		if index != want {
+			t.Fatalf("item %d from slot %d, want %d", i, index, want)
+		}
+		want = (want + 1) % q.cap
+	}
+
+	// 批量放入后依次取出，一轮内覆盖所有槽
+	seen := map[uint32]bool{}
+	for round := 0; round < 2; round++ {
+		q.PutSlice(ints(4))
+		for {
+			_, index, ok := q.GetIndexed()
+			if !ok {
+				break
+			}
+			if index >= q.cap {
+				t.Fatalf("index %d out of cap %d", index, q.cap)
+			}
+			seen[index] = true
+		}
+	}
+	if len(seen) != int(q.cap) {
+		t.Fatalf("indexes %v don't cover all %d slots", seen, q.cap)
+	}
+}
+
+func TestPutGetHandle(t *testing.T) {
+	q := newDefaultQueue(8)
+	handles := map[interface{}]uint64{}
+	var last uint64
+	// 几轮下来跨过环的末尾，句柄仍然递增
+	for round := 0; round < 4; round++ {
+		for i := 0; i < 5; i++ {
+			val := round*10 + i
+			h, ok := q.PutHandle(val)
+			if !ok || h <= last {
+				t.Fatalf("put %d: handle %d ok %v after %d, want monotonic", val, h, ok, last)
+			}
+			handles[val], last = h, h
+		}
+		for i := 0; i < 5; i++ {
+			val, h, ok := q.GetHandle()
+			if !ok || h != handles[val] {
+				t.Fatalf("get %v: handle %d ok %v, put with %d", val, h, ok, handles[val])
+			}
+		}
+	}
+
+	// 失败时为 0
+	q.PutSlice(ints(6))
+	if h, ok := q.PutHandle(-1); ok || h != 0 {
+		t.Fatalf("put into a full queue: handle %d ok %v", h, ok)
+	}
+	// 普通 Put 的数据也有句柄，就是它的写入位置
+	drainAll(t, q)
+	q.Put("plain")
+	if _, h, ok := q.GetHandle(); !ok || h != last+7 {
+		t.Fatalf("handle %d of a plain put, want %d", h, last+7)
+	}
+}
@@ -0,0 +1,60 @@
+package debughttp
+
+/*
+ @File : debughttp.go
+ @Description: serve the state of a queue over http as json, a separate package to keep net/http out of the core
+ @Time : 2026/10/15
+*/
+
+import (
+	"encoding/json"
+	"net/http"
+
+	queue "quequ"
+)
+
+// State the json body served by DebugHandler
+type State struct {
+	ID          string              `json:"id,omitempty"` // 见 queue.WithID
+	Capacity    uint32              `json:"cap"`
+	Count       uint32              `json:"count"`
+	Utilization float64             `json:"utilization"` // count / cap
+	Stats       *queue.QueueMetrics `json:"stats,omitempty"`
+}
+
+// statsQueue 能提供统计数据的队列，见 queue.WithStats
+type statsQueue interface {
+	Stats() queue.QueueMetrics
+	StatsEnabled() bool
+}
+
+// idQueue 设置了标识的队列，见 queue.WithID
+type idQueue interface {
+	ID() string
+}
+
+// DebugHandler serve the state of q as json on every request, such as
+// {"cap":1024,"count":10,"utilization":0.009765625,"stats":{"Puts":12,"Gets":2,"Full":0,"Empty":0}}.
+// cap and count are 0 if q is not a queue.InspectableQueue, stats is omitted if q can't report it
+// or is not created with queue.WithStats, so is id if not set
+func DebugHandler(q queue.Queue) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var s State
+		if iq, ok := q.(idQueue); ok {
+			s.ID = iq.ID()
+		}
+		if iq, ok := queue.AsInspectable(q); ok {
+			s.Capacity = iq.Capacity()
+			s.Count = iq.Count()
+			if s.Capacity > 0 {
+				s.Utilization = float64(s.Count) / float64(s.Capacity)
+			}
+		}
+		if sq, ok := q.(statsQueue); ok && sq.StatsEnabled() {
+			m := sq.Stats()
+			s.Stats = &m
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&s)
+	})
+}
@@ -0,0 +1,62 @@
+package debughttp
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	queue "quequ"
+)
+
+func serve(t *testing.T, q queue.Queue) State {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	DebugHandler(q).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/queue", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("content type %q", ct)
+	}
+	var s State
+	if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return s
+}
+
+func TestDebugHandler(t *testing.T) {
+	q := queue.NewQueue(8, queue.WithID("orders"), queue.WithStats())
+	for i := 0; i < 3; i++ {
+		q.Put(i)
+	}
+	q.Get()
+
+	s := serve(t, q)
+	if s.ID != "orders" || s.Capacity != 8 || s.Count != 2 || s.Utilization != 0.25 {
+		t.Fatalf("state %+v, want orders cap 8 count 2 utilization 0.25", s)
+	}
+	want := queue.QueueMetrics{Puts: 3, Gets: 1}
+	if s.Stats == nil || *s.Stats != want {
+		t.Fatalf("stats %+v, want %+v", s.Stats, want)
+	}
+}
+
+func TestDebugHandlerWithoutStats(t *testing.T) {
+	q := queue.NewQueue(8)
+	q.Put(1)
+
+	rec := httptest.NewRecorder()
+	DebugHandler(q).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var raw map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	// 没有 WithStats 时全是 0 的计数没有意义，不输出
+	if _, ok := raw["stats"]; ok {
+		t.Fatalf("stats served without WithStats: %s", rec.Body.String())
+	}
+	if _, ok := raw["id"]; ok {
+		t.Fatalf("id served without WithID: %s", rec.Body.String())
+	}
+	if s := serve(t, q); s.Capacity != 8 || s.Count != 1 {
+		t.Fatalf("state %+v, want cap 8 count 1", s)
+	}
+}
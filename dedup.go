@@ -0,0 +1,61 @@
+package queue
+
+/*
+ @File : dedup.go
+ @Description: queue which ignore the item whose key is already in queue
+ @Time : 2026/10/15
+*/
+
+import "sync"
+
+// DedupQueue reject Put if an item with the same key is still buffered,
+// the key is removed when the item is taken by Get.
+// The keys are kept in a map protected by a mutex, so Put is serialized and not lock free any more,
+// it trades lock freedom for dedup semantics
+type DedupQueue struct {
+	ring *DefaultQueue
+	key  func(val interface{}) string
+
+	mu   sync.Mutex
+	keys map[string]struct{} // 队列中数据的 key
+}
+
+// NewDedupQueue alloc a DedupQueue, key return the dedup key of each item
+func NewDedupQueue(cap uint32, key func(val interface{}) string) *DedupQueue {
+	return &DedupQueue{
+		ring: newDefaultQueue(cap),
+		key:  key,
+		keys: make(map[string]struct{}),
+	}
+}
+
+// Put May failed if the key is already in queue or lock slot failed or full
+func (q *DedupQueue) Put(val interface{}) (ok bool, count uint32) {
+	k := q.key(val)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, exist := q.keys[k]; exist {
+		return false, q.ring.Count()
+	}
+	if ok, count = q.ring.Put(val); ok {
+		q.keys[k] = struct{}{}
+	}
+	return ok, count
+}
+
+// Get May failed if lock slot failed or empty, the key of the item is removed
+func (q *DedupQueue) Get() (val interface{}, ok bool, count uint32) {
+	val, ok, count = q.ring.Get()
+	if ok {
+		q.mu.Lock()
+		delete(q.keys, q.key(val))
+		q.mu.Unlock()
+	}
+	return val, ok, count
+}
+
+// Count the number of items in queue
+func (q *DedupQueue) Count() uint32 {
+	return q.ring.Count()
+}
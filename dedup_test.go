@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDedupQueue(t *testing.T) {
+	q := NewDedupQueue(16, func(val interface{}) string {
+		return fmt.Sprint(val.([2]int)[0]) // 只按第一个数去重
+	})
+	if ok, _ := q.Put([2]int{1, 1}); !ok {
+		t.Fatal("first put of key 1 failed")
+	}
+	if ok, _ := q.Put([2]int{1, 2}); ok {
+		t.Fatal("duplicate key 1 accepted")
+	}
+	if ok, _ := q.Put([2]int{2, 1}); !ok {
+		t.Fatal("put of key 2 failed")
+	}
+	if q.Count() != 2 {
+		t.Fatalf("count %d, want 2", q.Count())
+	}
+
+	// 只有第一个被保留
+	if val, _, _ := q.Get(); val != [2]int{1, 1} {
+		t.Fatalf("got %v, want the first of key 1", val)
+	}
+	// 取走后 key 被删除，可以再放
+	if ok, _ := q.Put([2]int{1, 3}); !ok {
+		t.Fatal("key 1 rejected after taken")
+	}
+	if ok, _ := q.Put([2]int{2, 2}); ok {
+		t.Fatal("duplicate key 2 accepted")
+	}
+	for _, want := range [][2]int{{2, 1}, {1, 3}} {
+		if val, ok, _ := q.Get(); !ok || val != want {
+			t.Fatalf("got %v, want %v", val, want)
+		}
+	}
+}
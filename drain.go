@@ -0,0 +1,107 @@
+package queue
+
+/*
+ @File : drain.go
+ @Description: drain the items out of queue into other sinks
+ @Time : 2026/10/15
+*/
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// DrainFunc get items one by one and pass each to fn, until fn return false or the queue is empty,
+// return how many items drained, the item which fn returned false for is also drained.
+// The slots are cleared as the items leave
+func (q *DefaultQueue) DrainFunc(fn func(val interface{}) bool) int {
+	n := 0
+	for {
+		read := q.read.Load()
+		write := q.write.Load()
+		if q.posCount(read, write) < 1 {
+			return n
+		}
+
+		getPosNext := read + 1
+		if !q.casRead(read, getPosNext) {
+			runtime.Gosched()
+			continue
+		}
+		n++
+		if !fn(q.getAt(getPosNext)) {
+			return n
+		}
+	}
+}
+
+// DrainBuffers get all the items and pass each []byte to release, typically put it back to a sync.Pool,
+// return how many released, the items which are not []byte are dropped.
+// The slots are cleared as the items leave, so the queue holds no reference to the buffers after
+func (q *DefaultQueue) DrainBuffers(release func([]byte)) int {
+	n := 0
+	q.DrainFunc(func(val interface{}) bool {
+		if buf, ok := val.([]byte); ok {
+			release(buf)
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// DrainTimeout get items into dst in order until dst is full, the queue is empty or d elapses,
+// return how many got, dst[n:] is not touched.
+// Bound the time of draining on shutdown while producers keep putting, the deadline is checked between batches
+func (q *DefaultQueue) DrainTimeout(dst []interface{}, d time.Duration) int {
+	deadline := time.Now().Add(d)
+	n := 0
+	for n < len(dst) && time.Now().Before(deadline) {
+		gets, count := q.Gets(dst[n:])
+		if gets == 0 && count == 0 {
+			return n
+		}
+		n += int(gets)
+	}
+	return n
+}
+
+// DrainToChannel forward the items buffered at the moment of call to the returned channel,
+// and close it after that many items forwarded or the queue is empty (taken by other consumers)
+// or ctx is done, the items put after the call are not forwarded.
+// The item already got from queue is dropped if ctx is done before the receiver takes it
+func (q *DefaultQueue) DrainToChannel(ctx context.Context) <-chan interface{} {
+	n := q.Count()
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		for i := uint32(0); i < n; {
+			if ctx.Err() != nil {
+				return
+			}
+			val, ok, cnt := q.Get()
+			if !ok {
+				if cnt == 0 {
+					return
+				}
+				continue
+			}
+			select {
+			case ch <- val:
+				i++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// GetLast the same as Get, and wasLast is true when the queue is empty after this get,
+// the consumer can do finalization on the last item when shutting down.
+// Approximate if producers are still putting, a new item may come right after
+func (q *DefaultQueue) GetLast() (val interface{}, ok bool, wasLast bool) {
+	val, ok, count := q.Get()
+	return val, ok, ok && count == 0
+}
@@ -0,0 +1,198 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDrainFunc(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(10))
+
+	var got []interface{}
+	n := q.DrainFunc(func(val interface{}) bool {
+		got = append(got, val)
+		return true
+	})
+	if n != 10 {
+		t.Fatalf("drained %d, want 10", n)
+	}
+	assertSeq(t, got, 0, 10)
+	for i := range q.carrier {
+		if q.carrier[i].value != nil {
+			t.Fatalf("slot %d not cleared", i)
+		}
+	}
+	if n := q.DrainFunc(func(interface{}) bool { return true }); n != 0 {
+		t.Fatalf("drained %d from empty queue", n)
+	}
+}
+
+func TestDrainFuncStop(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(10))
+
+	// 返回 false 的那个也被取走
+	n := q.DrainFunc(func(val interface{}) bool {
+		return val != 3
+	})
+	if n != 4 {
+		t.Fatalf("drained %d, want 4", n)
+	}
+	assertSeq(t, drainAll(t, q), 4, 6)
+}
+
+func TestDrainToChannel(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(5))
+
+	ch := q.DrainToChannel(context.Background())
+	// 调用之后放入的不会转发
+	q.Put(100)
+	var got []interface{}
+	for val := range ch {
+		got = append(got, val)
+	}
+	assertSeq(t, got, 0, 5)
+	if val, _, _ := q.Get(); val != 100 {
+		t.Fatalf("left %v in queue, want 100", val)
+	}
+}
+
+func TestDrainToChannelCancel(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(5))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := q.DrainToChannel(ctx)
+	if val := <-ch; val != 0 {
+		t.Fatalf("got %v, want 0", val)
+	}
+	cancel()
+	// 取消后通道关闭
+	for range ch {
+	}
+	if q.Count() < 3 {
+		t.Fatalf("count %d after cancel, want the rest mostly left", q.Count())
+	}
+}
+
+func TestDrainToChannelEmpty(t *testing.T) {
+	q := newDefaultQueue(16)
+	if _, open := <-q.DrainToChannel(context.Background()); open {
+		t.Fatal("channel of empty queue not closed")
+	}
+}
+
+func TestGetLast(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(5))
+
+	for i := 0; i < 5; i++ {
+		val, ok, wasLast := q.GetLast()
+		if !ok || val != i {
+			t.Fatalf("get %v %v, want %d", val, ok, i)
+		}
+		if wasLast != (i == 4) {
+			t.Fatalf("item %d wasLast %v", i, wasLast)
+		}
+	}
+	// 空队列取不到，也不是最后一个
+	if _, ok, wasLast := q.GetLast(); ok || wasLast {
+		t.Fatalf("get on empty returned ok %v wasLast %v", ok, wasLast)
+	}
+
+	// 取完之后再放入，新的最后一个同样能识别
+	q.Put("again")
+	if val, ok, wasLast := q.GetLast(); !ok || val != "again" || !wasLast {
+		t.Fatalf("get %v %v wasLast %v", val, ok, wasLast)
+	}
+}
+
+func TestDrainBuffers(t *testing.T) {
+	q := newDefaultQueue(16)
+	bufs := make([][]byte, 10)
+	for i := range bufs {
+		bufs[i] = []byte{byte(i)}
+		q.Put(bufs[i])
+	}
+	q.Put("not a buffer") // 不是 []byte 的丢弃，不交给 release
+
+	released := map[*byte]int{}
+	var order []byte
+	n := q.DrainBuffers(func(buf []byte) {
+		released[&buf[0]]++
+		order = append(order, buf[0])
+	})
+	if n != 10 {
+		t.Fatalf("released %d, want 10", n)
+	}
+	for i, buf := range bufs {
+		if released[&buf[0]] != 1 {
+			t.Fatalf("buffer %d released %d times", i, released[&buf[0]])
+		}
+		if order[i] != byte(i) {
+			t.Fatalf("release order %v", order)
+		}
+	}
+	if q.Count() != 0 {
+		t.Fatalf("count %d after DrainBuffers", q.Count())
+	}
+	for i := range q.carrier {
+		if q.carrier[i].value != nil {
+			t.Fatalf("slot %d still holds %v", i, q.carrier[i].value)
+		}
+	}
+}
+
+func TestDrainTimeoutDeadline(t *testing.T) {
+	var q *DefaultQueue
+	next := 6
+	// 生产者一直在放：每取走一个就放入一个，取一个要 100µs，队列永远不会空
+	q = newDefaultQueue(8, WithCopyOnGet(func(v interface{}) interface{} {
+		q.Put(next)
+		next++
+		time.Sleep(100 * time.Microsecond)
+		return v
+	}))
+	q.PutSlice(ints(6))
+
+	dst := make([]interface{}, 100000)
+	const d = 20 * time.Millisecond
+	start := time.Now()
+	n := q.DrainTimeout(dst, d)
+	elapsed := time.Since(start)
+	if n == 0 || n == len(dst) {
+		t.Fatalf("drained %d, want a partial result", n)
+	}
+	// 每批之间检查截止时间，超出不会多于一批
+	if elapsed < d || elapsed > d+50*time.Millisecond {
+		t.Fatalf("drain took %v with a budget of %v", elapsed, d)
+	}
+	assertSeq(t, dst[:n], 0, n)
+	if dst[n] != nil {
+		t.Fatalf("dst[%d] touched: %v", n, dst[n])
+	}
+}
+
+func TestDrainTimeoutFullOrEmpty(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(10))
+	// dst 满了就返回
+	dst := make([]interface{}, 4)
+	if n := q.DrainTimeout(dst, time.Hour); n != 4 {
+		t.Fatalf("drained %d, want 4", n)
+	}
+	assertSeq(t, dst, 0, 4)
+	// 取空就返回，不等到截止时间
+	dst = make([]interface{}, 16)
+	start := time.Now()
+	if n := q.DrainTimeout(dst, time.Hour); n != 6 {
+		t.Fatalf("drained %d, want the remaining 6", n)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("drain of an empty queue waited for the deadline")
+	}
+	assertSeq(t, dst[:6], 4, 6)
+}
@@ -0,0 +1,49 @@
+package queue
+
+/*
+ @File : envelope.go
+ @Description: queue which carry key-value metadata with each item
+ @Time : 2026/10/15
+*/
+
+// EnvelopeQueue carry a metadata map with each item, such as trace id,
+// so the middleware don't need to wrap every value by itself.
+// The value and the map are boxed together, each Put allocates one envelope.
+// The map is passed by reference, don't modify it after Put
+type EnvelopeQueue struct {
+	ring *DefaultQueue // 槽里放的都是 *envelope
+}
+
+// envelope 数据和元数据装在一起放进 ring，普通队列的槽不需要为元数据留位置
+type envelope struct {
+	value interface{}
+	meta  map[string]string
+}
+
+// NewEnvelopeQueue alloc an EnvelopeQueue
+func NewEnvelopeQueue(cap uint32) *EnvelopeQueue {
+	return &EnvelopeQueue{ring: newDefaultQueue(cap)}
+}
+
+// Put put val with meta, May failed if lock slot failed or full
+func (q *EnvelopeQueue) Put(val interface{}, meta map[string]string) (ok bool, count uint32) {
+	return q.ring.Put(&envelope{value: val, meta: meta})
+}
+
+// Get get the head item with its meta, the slot is cleared including the meta
+func (q *EnvelopeQueue) Get() (val interface{}, meta map[string]string, ok bool) {
+	v, ok, _ := q.ring.Get()
+	if !ok {
+		return nil, nil, false
+	}
+	env := v.(*envelope)
+	if env.value == nil {
+		return nil, nil, false
+	}
+	return env.value, env.meta, true
+}
+
+// Count the number of items in queue
+func (q *EnvelopeQueue) Count() uint32 {
+	return q.ring.Count()
+}
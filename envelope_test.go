@@ -0,0 +1,55 @@
+package queue
+
+import "testing"
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	q := NewEnvelopeQueue(8)
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 6; i++ {
+			meta := map[string]string{"trace": string(rune('a' + i))}
+			if i == 3 {
+				meta = nil // 没有元数据也可以
+			}
+			if ok, _ := q.Put(i, meta); !ok {
+				t.Fatalf("round %d: put %d failed", round, i)
+			}
+		}
+		for i := 0; i < 6; i++ {
+			val, meta, ok := q.Get()
+			if !ok || val != i {
+				t.Fatalf("round %d: get %v %v, want %d", round, val, ok, i)
+			}
+			if i == 3 {
+				if meta != nil {
+					t.Fatalf("round %d: item 3 got meta %v", round, meta)
+				}
+				continue
+			}
+			if meta["trace"] != string(rune('a'+i)) {
+				t.Fatalf("round %d: item %d got meta %v", round, i, meta)
+			}
+		}
+		// 取走后槽里的信封清空了，元数据跟着一起释放
+		for i := range q.ring.carrier {
+			if s := &q.ring.carrier[i]; s.value != nil {
+				t.Fatalf("round %d: slot %d holds %v", round, i, s.value)
+			}
+		}
+	}
+	if _, _, ok := q.Get(); ok || q.Count() != 0 {
+		t.Fatal("got from empty queue")
+	}
+}
+
+func TestEnvelopeNilValue(t *testing.T) {
+	q := NewEnvelopeQueue(8)
+	q.Put(nil, map[string]string{"trace": "x"})
+	q.Put(1, nil)
+	// 和 DefaultQueue 一样，nil 的数据当作没取到
+	if val, meta, ok := q.Get(); ok || val != nil || meta != nil {
+		t.Fatalf("got %v %v %v for a nil value", val, meta, ok)
+	}
+	if val, _, ok := q.Get(); !ok || val != 1 {
+		t.Fatalf("got %v %v, want 1", val, ok)
+	}
+}
@@ -0,0 +1,22 @@
+package queue
+
+/*
+ @File : errors.go
+ @Description: sentinel errors, compare with errors.Is
+ @Time : 2026/10/15
+*/
+
+import "errors"
+
+var (
+	ErrClosed  = errors.New("queue closed")  // 队列已经关闭，或者重复关闭
+	ErrFull    = errors.New("queue full")    // 队列满了
+	ErrTimeout = errors.New("queue timeout") // 等待超时
+
+	ErrInvalidCapacity = errors.New("queue invalid capacity") // 容量不是2的幂次或者太小
+
+	ErrConcurrentConsumer = errors.New("queue concurrent consumer") // 只允许一个消费者的操作遇到了其他消费者
+
+	errConflict = errors.New("queue lock slot failed")                      // 占位失败，可以马上重试
+	errNotPlain = errors.New("queue item with extra info can't be spilled") // 带有回调等附带信息的数据不能写到磁盘
+)
@@ -0,0 +1,40 @@
+package queue
+
+/*
+ @File : evict.go
+ @Description: evict the stale items by the put time, need WithTimestamp
+ @Time : 2026/10/15
+*/
+
+import "time"
+
+// EvictOlderThan remove the items put before cutoff from the head, return how many evicted.
+// The queue is FIFO so the old items are near the head, it stops at the first item not older than cutoff,
+// a stale item behind a newer one (possible under concurrent producers) is kept.
+// Need WithTimestamp, otherwise nothing is evicted.
+// Must be the ONLY consumer or called when the queue is quiesced, the head is checked before taken
+func (q *DefaultQueue) EvictOlderThan(cutoff time.Time) int {
+	if !q.stamp {
+		return 0
+	}
+	limit := cutoff.UnixNano()
+	n := 0
+	for {
+		read := q.read.Load()
+		write := q.write.Load()
+		if q.posCount(read, write) < 1 {
+			return n
+		}
+
+		getPosNext := read + 1
+		q.peekAt(getPosNext) // 等待写入完成，时间戳才是对的
+		if q.carrier[getPosNext&q.capMod].stamp() >= limit {
+			return n
+		}
+		if !q.casRead(read, getPosNext) {
+			continue
+		}
+		q.getAt(getPosNext)
+		n++
+	}
+}
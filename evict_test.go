@@ -0,0 +1,46 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvictOlderThan(t *testing.T) {
+	q := newDefaultQueue(16, WithTimestamp())
+	q.PutSlice(ints(3))
+	time.Sleep(time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(time.Millisecond)
+	for i := 3; i < 5; i++ {
+		q.Put(i)
+	}
+
+	if n := q.EvictOlderThan(cutoff); n != 3 {
+		t.Fatalf("evicted %d, want 3", n)
+	}
+	assertSeq(t, drainAll(t, q), 3, 2)
+	if n := q.EvictOlderThan(time.Now()); n != 0 {
+		t.Fatalf("evicted %d from empty queue", n)
+	}
+}
+
+func TestEvictOlderThanStopsAtNewer(t *testing.T) {
+	q := newDefaultQueue(16, WithTimestamp())
+	now := time.Now()
+	// 并发的生产者可能让旧的排在新的后面，扫描到第一个新的就停
+	for i, age := range []time.Duration{time.Hour, time.Minute, 0, time.Hour} {
+		q.put(entry{value: i, x: &extra{stamp: now.Add(-age).UnixNano()}})
+	}
+	if n := q.EvictOlderThan(now.Add(-time.Second)); n != 2 {
+		t.Fatalf("evicted %d, want 2", n)
+	}
+	assertSeq(t, drainAll(t, q), 2, 2)
+}
+
+func TestEvictOlderThanWithoutTimestamp(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(3))
+	if n := q.EvictOlderThan(time.Now().Add(time.Hour)); n != 0 {
+		t.Fatalf("evicted %d without WithTimestamp", n)
+	}
+}
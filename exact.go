@@ -0,0 +1,134 @@
+package queue
+
+/*
+ @File : exact.go
+ @Description: queue with exact capacity, not rounded to 2's power,
+               use % instead of & capMod to locate the slot
+ @Time : 2026/10/15
+*/
+
+import (
+	"math"
+	"runtime"
+
+	"go.uber.org/atomic"
+)
+
+// ExactQueue allocate exactly cap slots and hold at most cap - 1 items.
+// The slot is located by % cap instead of the bitmask of DefaultQueue,
+// a division on each operation, a bit slower, use NewQueue if the exact size is not required
+type ExactQueue struct {
+	cap     uint32
+	limit   uint32 // 读写位置在 [0, limit) 内回绕，limit 是 cap 的整数倍，回绕后 pos % cap 依然连续
+	write   *atomic.Uint32
+	read    *atomic.Uint32
+	carrier []slot
+}
+
+// NewExactQueue alloc an ExactQueue with exactly cap slots, cap is at least 2
+func NewExactQueue(cap uint32) *ExactQueue {
+	if cap < 2 {
+		cap = 2
+	}
+	q := &ExactQueue{
+		cap:     cap,
+		limit:   math.MaxUint32 / cap * cap,
+		write:   atomic.NewUint32(0),
+		read:    atomic.NewUint32(0),
+		carrier: make([]slot, cap),
+	}
+	// 和 DefaultQueue 一样，位置从 1 开始，0 号槽第一次使用的位置是 cap
+	var i uint32
+	for ; i < cap; i++ {
+		id := i
+		if i == 0 {
+			id = cap
+		}
+		q.carrier[i].writeID = atomic.NewUint32(id)
+		q.carrier[i].readID = atomic.NewUint32(id)
+	}
+	return q
+}
+
+// Put May failed if lock slot failed or full
+func (q *ExactQueue) Put(val interface{}) (ok bool, count uint32) {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := q.count(read, write)
+	if cnt >= q.cap-1 {
+		runtime.Gosched()
+		return false, cnt
+	}
+
+	posNext := q.next(write, 1)
+	if !q.write.CAS(write, posNext) {
+		runtime.Gosched()
+		return false, cnt
+	}
+
+	cache := &q.carrier[posNext%q.cap]
+	for {
+		readID := cache.readID.Load()
+		writeID := cache.writeID.Load()
+		if posNext == writeID && readID == writeID {
+			cache.value = val
+			cache.writeID.Store(q.next(writeID, q.cap)) // 只有占到该位置的生产者会修改，直接 Store
+			return true, cnt + 1
+		}
+		runtime.Gosched()
+	}
+}
+
+// Get May failed if lock slot failed or empty
+func (q *ExactQueue) Get() (val interface{}, ok bool, count uint32) {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := q.count(read, write)
+	if cnt < 1 {
+		runtime.Gosched()
+		return nil, false, cnt
+	}
+
+	getPosNext := q.next(read, 1)
+	if !q.read.CAS(read, getPosNext) {
+		runtime.Gosched()
+		return nil, false, cnt
+	}
+
+	cache := &q.carrier[getPosNext%q.cap]
+	for {
+		readID := cache.readID.Load()
+		writeID := cache.writeID.Load()
+		if getPosNext == readID && q.next(readID, q.cap) == writeID {
+			val = cache.value
+			cache.value = nil
+			cache.readID.Store(q.next(readID, q.cap))
+			if val == nil {
+				return nil, false, cnt - 1
+			}
+			return val, true, cnt - 1
+		}
+		runtime.Gosched()
+	}
+}
+
+// Count the number of items in queue
+func (q *ExactQueue) Count() uint32 {
+	return q.count(q.read.Load(), q.write.Load())
+}
+
+// Capacity the exact number of slots
+func (q *ExactQueue) Capacity() uint32 {
+	return q.cap
+}
+
+// next pos 向后移动 delta，在 limit 处回绕
+func (q *ExactQueue) next(pos, delta uint32) uint32 {
+	return uint32((uint64(pos) + uint64(delta)) % uint64(q.limit))
+}
+
+func (q *ExactQueue) count(read, write uint32) uint32 {
+	return uint32((uint64(write) + uint64(q.limit) - uint64(read)) % uint64(q.limit))
+}
@@ -0,0 +1,101 @@
+package queue
+
+import "testing"
+
+func TestExactQueueHoldsCapMinusOne(t *testing.T) {
+	for _, c := range []uint32{2, 3, 5, 10, 100} {
+		q := NewExactQueue(c)
+		if q.Capacity() != c || len(q.carrier) != int(c) {
+			t.Fatalf("cap %d: Capacity %d, %d slots", c, q.Capacity(), len(q.carrier))
+		}
+		for i := uint32(0); i < c-1; i++ {
+			if ok, _ := q.Put(i); !ok {
+				t.Fatalf("cap %d: put %d failed", c, i)
+			}
+		}
+		if ok, cnt := q.Put(c); ok || cnt != c-1 {
+			t.Fatalf("cap %d: put on full returned %v count %d", c, ok, cnt)
+		}
+		if q.Count() != c-1 {
+			t.Fatalf("cap %d: count %d", c, q.Count())
+		}
+		for i := uint32(0); i < c-1; i++ {
+			val, ok, _ := q.Get()
+			if !ok || val != i {
+				t.Fatalf("cap %d: get %v %v, want %d", c, val, ok, i)
+			}
+		}
+		if _, ok, _ := q.Get(); ok {
+			t.Fatalf("cap %d: get on empty succeeded", c)
+		}
+	}
+}
+
+func TestExactQueueFIFOAcrossRounds(t *testing.T) {
+	q := NewExactQueue(5)
+	next, want := 0, 0
+	// 每轮放入和取出的数量不同，让位置在 5 个槽之间错开回绕很多轮
+	for round := 0; round < 50; round++ {
+		for i := 0; i < round%4+1; i++ {
+			if ok, _ := q.Put(next); ok {
+				next++
+			}
+		}
+		for i := 0; i < round%3+1; i++ {
+			val, ok, _ := q.Get()
+			if !ok {
+				break
+			}
+			if val != want {
+				t.Fatalf("round %d: got %v, want %d", round, val, want)
+			}
+			want++
+		}
+	}
+	for {
+		val, ok, _ := q.Get()
+		if !ok {
+			break
+		}
+		if val != want {
+			t.Fatalf("got %v, want %d", val, want)
+		}
+		want++
+	}
+	if want != next {
+		t.Fatalf("got %d items, put %d", want, next)
+	}
+}
+
+func TestExactQueueLimitWrap(t *testing.T) {
+	q := NewExactQueue(10)
+	// 把读写位置挪到 limit 前面，槽的 ID 设置成之后第一次用到它的位置，接下来的位置会在 limit 处回绕到 0
+	base := q.limit - 4
+	q.write.Store(base)
+	q.read.Store(base)
+	for k := uint32(1); k <= q.cap; k++ {
+		pos := q.next(base, k)
+		q.carrier[pos%q.cap].writeID.Store(pos)
+		q.carrier[pos%q.cap].readID.Store(pos)
+	}
+
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 9; i++ {
+			if ok, _ := q.Put(round*9 + i); !ok {
+				t.Fatalf("round %d: put %d failed, count %d", round, i, q.Count())
+			}
+		}
+		if ok, _ := q.Put(-1); ok {
+			t.Fatalf("round %d: put on full succeeded", round)
+		}
+		for i := 0; i < 9; i++ {
+			val, ok, _ := q.Get()
+			if !ok || val != round*9+i {
+				t.Fatalf("round %d: get %v %v, want %d", round, val, ok, round*9+i)
+			}
+		}
+	}
+	if w := q.write.Load(); w >= base {
+		t.Fatalf("write %d didn't wrap at limit %d", w, q.limit)
+	}
+}
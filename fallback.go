@@ -0,0 +1,75 @@
+package queue
+
+/*
+ @File : fallback.go
+ @Description: mutex mode for Put/Get under extreme contention, see WithAutoFallback
+ @Time : 2026/10/15
+*/
+
+import (
+	"sync"
+
+	"go.uber.org/atomic"
+)
+
+// fallbackCalm 加锁模式下连续多少次操作没有其他等待者，就切换回无锁模式
+const fallbackCalm = 1024
+
+type fallback struct {
+	threshold uint32
+	fails     *atomic.Uint32 // 连续 CAS 失败的次数
+	on        *atomic.Bool   // 是否处于加锁模式
+
+	mu      sync.Mutex
+	waiting *atomic.Int32 // 等待锁的个数
+	calm    uint32        // 加锁模式下连续没有等待者的次数，持有锁时修改
+}
+
+func newFallback(threshold uint32) *fallback {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &fallback{
+		threshold: threshold,
+		fails:     atomic.NewUint32(0),
+		on:        atomic.NewBool(false),
+		waiting:   atomic.NewInt32(0),
+	}
+}
+
+// record 记录一次 CAS 的结果，连续失败超过阈值后进入加锁模式
+func (f *fallback) record(ok bool) bool {
+	if ok {
+		if f.fails.Load() != 0 {
+			f.fails.Store(0)
+		}
+		return true
+	}
+	if f.fails.Inc() >= f.threshold && !f.on.Load() {
+		f.on.Store(true)
+	}
+	return false
+}
+
+// lock 加锁模式下 Put/Get 先拿锁，锁内仍然走原来的 CAS 流程，
+// 和切换前还在进行的无锁操作混在一起也是对的
+func (f *fallback) lock() {
+	f.waiting.Inc()
+	f.mu.Lock()
+	f.waiting.Dec()
+}
+
+// unlock 释放锁，长时间没有其他等待者说明竞争已经缓解，切换回无锁模式
+func (f *fallback) unlock() {
+	if f.waiting.Load() == 0 {
+		f.calm++
+	} else {
+		f.calm = 0
+	}
+	if f.calm >= fallbackCalm {
+		f.calm = 0
+		f.fails.Store(0)
+		f.on.Store(false)
+	}
+	f.mu.Unlock()
+}
@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFallbackSwitching(t *testing.T) {
+	f := newFallback(3)
+	f.record(false)
+	f.record(false)
+	if f.on.Load() {
+		t.Fatal("switched before threshold")
+	}
+	// 成功一次后重新计数
+	f.record(true)
+	f.record(false)
+	f.record(false)
+	if f.on.Load() {
+		t.Fatal("switched without threshold failures in a row")
+	}
+	f.record(false)
+	if !f.on.Load() {
+		t.Fatal("not switched after threshold failures in a row")
+	}
+
+	// 加锁模式下一直没有等待者，切换回无锁模式
+	for i := 0; i < fallbackCalm; i++ {
+		f.lock()
+		f.unlock()
+	}
+	if f.on.Load() {
+		t.Fatalf("still in mutex mode after %d calm operations", fallbackCalm)
+	}
+}
+
+func TestFallbackFIFOAndNoLoss(t *testing.T) {
+	// 阈值为 1，任何一次冲突都切换到加锁模式，并发过程中反复切换
+	q := newDefaultQueue(16, WithAutoFallback(1))
+	produceConsume(t, q, 4, 1, 5000)
+	produceConsume(t, q, 4, 4, 5000)
+
+	// 一开始就处在加锁模式
+	q = newDefaultQueue(16, WithAutoFallback(1))
+	q.fb.on.Store(true)
+	// 单核上一直重试的生产者抢锁很慢，数量少一些
+	produceConsume(t, q, 4, 1, 200)
+	for round := 0; round < 5; round++ {
+		n := q.PutSlice(ints(20))
+		assertSeq(t, drainAll(t, q), 0, n)
+	}
+}
+
+func TestFallbackSwitchMidway(t *testing.T) {
+	q := newDefaultQueue(16, WithAutoFallback(1<<30))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// 无锁操作进行中切换到加锁模式，两种模式的操作混在一起
+		for i := 0; i < 200; i++ {
+			q.fb.on.Store(i%2 == 0)
+		}
+	}()
+	produceConsume(t, q, 4, 1, 1000)
+	wg.Wait()
+}
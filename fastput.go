@@ -0,0 +1,36 @@
+package queue
+
+/*
+ @File : fastput.go
+ @Description: Put without loading read, decide full from the state of the slot ahead
+ @Time : 2026/10/15
+*/
+
+// PutFast put val into queue like Put, but skip the count check in the common case,
+// whether there is room is decided by the slot ahead of the reserved position, only write is loaded.
+// Fall back to Put when the slot is not clearly free, or closed, or space reserved,
+// or any option hooking each Put is set: WithSpillover, WithAutoFallback, WithCallerTracking,
+// WithLatencyHook and WithStrictChecks.
+// It never puts more than Put does, return false if failed
+func (q *DefaultQueue) PutFast(val interface{}) bool {
+	if !q.fastPutable() || q.closed.Load() || q.reserved.Load() != 0 {
+		ok, _ := q.Put(val)
+		return ok
+	}
+	write := q.write.Load()
+	// 放入后数量不超过 capMod-1，即 read >= write+3-cap，等价于位置 write+3-cap 已经被取走，
+	// 该位置的槽被取走后 readID == writeID == write+3，read 只会增加，只要 write 没变这个判断就一直成立
+	ahead := &q.carrier[(write+3)&q.capMod]
+	if ahead.readID.Load() != write+3 || ahead.writeID.Load() != write+3 || !q.casWrite(write, write+1) {
+		ok, _ := q.Put(val)
+		return ok
+	}
+	q.putAt(write+1, entry{value: val})
+	q.onPut(1)
+	return true
+}
+
+// fastPutable 没有需要在每次 Put 时处理的 option，快速路径才能跳过 putPosErr
+func (q *DefaultQueue) fastPutable() bool {
+	return q.spill == nil && q.fb == nil && !q.tracking && q.latencyHook == nil && !q.checks
+}
@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fastQueue 用 PutFast 代替 Put 的 Queue，给 produceConsume 使用
+type fastQueue struct {
+	*DefaultQueue
+}
+
+func (q fastQueue) Put(val interface{}) (bool, uint32) {
+	return q.PutFast(val), 0
+}
+
+func TestPutFastNoOverfill(t *testing.T) {
+	q := newDefaultQueue(8)
+	for round := 0; round < 200; round++ {
+		// 多个生产者同时往快满的队列里放，成功的总数不能超过 Put 能放的 6 个
+		var mu sync.Mutex
+		puts := 0
+		var wg sync.WaitGroup
+		for p := 0; p < 4; p++ {
+			wg.Add(1)
+			go func(p int) {
+				defer wg.Done()
+				for i := 0; i < 10; i++ {
+					if q.PutFast(p*10 + i) {
+						mu.Lock()
+						puts++
+						mu.Unlock()
+					}
+				}
+			}(p)
+		}
+		wg.Wait()
+		if puts != 6 || q.Count() != 6 {
+			t.Fatalf("round %d: %d puts succeeded, count %d, want 6", round, puts, q.Count())
+		}
+		if vals := drainAll(t, q); len(vals) != 6 {
+			t.Fatalf("round %d: drained %d", round, len(vals))
+		}
+	}
+}
+
+func TestPutFastNearCapacityStress(t *testing.T) {
+	// 小队列一直处在快满的状态，快速路径和回退到 Put 交替发生
+	produceConsume(t, fastQueue{newDefaultQueue(8)}, 4, 2, 20000)
+	produceConsume(t, fastQueue{newDefaultQueue(8)}, 4, 1, 20000)
+}
+
+func TestPutFastFallsBackForHooks(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opt  Option
+	}{
+		{"spill", WithSpillover(t.TempDir())},
+		{"fallback", WithAutoFallback(4)},
+		{"tracking", WithCallerTracking()},
+		{"latency", WithLatencyHook(func(string, time.Duration) {})},
+		{"checks", WithStrictChecks()},
+	} {
+		if newDefaultQueue(8, c.opt).fastPutable() {
+			t.Fatalf("%s: fast path taken", c.name)
+		}
+	}
+	if !newDefaultQueue(8, WithStats()).fastPutable() {
+		t.Fatal("fast path not taken without per put hooks")
+	}
+
+	var ops []string
+	q := newDefaultQueue(8, WithLatencyHook(func(op string, d time.Duration) { ops = append(ops, op) }))
+	q.PutFast(1)
+	if len(ops) != 1 || ops[0] != "put" {
+		t.Fatalf("latency hook got %v", ops)
+	}
+
+	q = newDefaultQueue(8, WithCallerTracking())
+	q.PutFast(1)
+	if label := q.callers[1&q.capMod].Load(); label == "" {
+		t.Fatal("caller not tracked by PutFast")
+	}
+}
+
+func TestPutFastHonorsFallbackLock(t *testing.T) {
+	q := newDefaultQueue(8, WithAutoFallback(4))
+	q.fb.on.Store(true)
+	q.fb.lock()
+	done := make(chan bool)
+	go func() { done <- q.PutFast(1) }()
+	// 加锁模式下 PutFast 也要等锁
+	select {
+	case <-done:
+		t.Fatal("PutFast bypassed the fallback mutex")
+	case <-time.After(10 * time.Millisecond):
+	}
+	q.fb.unlock()
+	if !<-done {
+		t.Fatal("PutFast failed after unlock")
+	}
+}
+
+func BenchmarkPutGet(b *testing.B) {
+	q := newDefaultQueue(1024)
+	for i := 0; i < b.N; i++ {
+		q.Put(i)
+		q.Get()
+	}
+}
+
+func BenchmarkPutFastGet(b *testing.B) {
+	q := newDefaultQueue(1024)
+	for i := 0; i < b.N; i++ {
+		q.PutFast(i)
+		q.Get()
+	}
+}
+
+func BenchmarkPutFastThroughput(b *testing.B) {
+	q := newDefaultQueue(1024)
+	benchPair(b,
+		func(v interface{}) bool { return q.PutFast(v) },
+		func() bool { _, ok, _ := q.Get(); return ok })
+}
+
+func BenchmarkPutThroughput(b *testing.B) {
+	q := newDefaultQueue(1024)
+	benchPair(b,
+		func(v interface{}) bool { ok, _ := q.Put(v); return ok },
+		func() bool { _, ok, _ := q.Get(); return ok })
+}
@@ -0,0 +1,172 @@
+package queue
+
+/*
+ @File : generic.go
+ @Description: typed ring with the same commit protocol as DefaultQueue, values are stored as T without boxing
+ @Time : 2026/10/15
+*/
+
+import (
+	"runtime"
+
+	"go.uber.org/atomic"
+)
+
+// slotT 同 slot，值直接按 T 保存
+type slotT[T any] struct {
+	writeID atomic.Uint32
+	readID  atomic.Uint32
+	value   T
+}
+
+// QueueT typed version of DefaultQueue, Put/Get of value types don't allocate.
+// Only the basic and batch methods, none of the options of DefaultQueue
+type QueueT[T any] struct {
+	cap     uint32
+	capMod  uint32
+	write   *atomic.Uint32
+	read    *atomic.Uint32
+	carrier []slotT[T]
+}
+
+// NewQueueT alloc a QueueT, cap is rounded the same as NewQueue
+func NewQueueT[T any](cap uint32) *QueueT[T] {
+	q := &QueueT[T]{
+		cap:   minRoundNumBy2(cap),
+		write: atomic.NewUint32(0),
+		read:  atomic.NewUint32(0),
+	}
+	q.capMod = q.cap - 1
+	q.carrier = make([]slotT[T], q.cap)
+	for i := uint32(0); i < q.cap; i++ {
+		id := i
+		if i == 0 {
+			id = q.cap // 同 initID
+		}
+		q.carrier[i].writeID.Store(id)
+		q.carrier[i].readID.Store(id)
+	}
+	return q
+}
+
+// Put May failed if lock slot failed or full, the same as DefaultQueue.Put
+func (q *QueueT[T]) Put(val T) (ok bool, count uint32) {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := posCount(read, write, q.cap)
+	if cnt >= q.capMod-1 {
+		runtime.Gosched()
+		return false, cnt
+	}
+	if !q.write.CAS(write, write+1) {
+		runtime.Gosched()
+		return false, cnt
+	}
+	q.putAt(write+1, val)
+	return true, cnt + 1
+}
+
+// Get May failed if lock slot failed or empty, the same as DefaultQueue.Get,
+// val is the zero value of T when ok is false
+func (q *QueueT[T]) Get() (val T, ok bool, count uint32) {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := posCount(read, write, q.cap)
+	if cnt < 1 {
+		runtime.Gosched()
+		return val, false, cnt
+	}
+	if !q.read.CAS(read, read+1) {
+		runtime.Gosched()
+		return val, false, cnt
+	}
+	return q.takeAt(read + 1), true, cnt - 1
+}
+
+// PutN put vals in order with one CAS, return how many put,
+// only vals[:n] are put if there is not enough room, 0 if full or lock positions failed, see DefaultQueue.Puts
+func (q *QueueT[T]) PutN(vals []T) int {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := posCount(read, write, q.cap)
+	if len(vals) == 0 || cnt >= q.capMod-1 {
+		return 0
+	}
+	n := q.capMod - 1 - cnt
+	if uint32(len(vals)) < n {
+		n = uint32(len(vals))
+	}
+	if !q.write.CAS(write, write+n) {
+		runtime.Gosched()
+		return 0
+	}
+	for i := uint32(0); i < n; i++ {
+		q.putAt(write+1+i, vals[i])
+	}
+	return int(n)
+}
+
+// GetN get min(len(dst), count) items into dst in order with one CAS, return how many got,
+// dst[n:] is not touched, 0 if empty or lock positions failed, see DefaultQueue.Gets
+func (q *QueueT[T]) GetN(dst []T) int {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := posCount(read, write, q.cap)
+	n := cnt
+	if uint32(len(dst)) < n {
+		n = uint32(len(dst))
+	}
+	if n == 0 || !q.read.CAS(read, read+n) {
+		runtime.Gosched()
+		return 0
+	}
+	for i := uint32(0); i < n; i++ {
+		dst[i] = q.takeAt(read + 1 + i)
+	}
+	return int(n)
+}
+
+// Count the number of items in queue
+func (q *QueueT[T]) Count() uint32 {
+	return posCount(q.read.Load(), q.write.Load(), q.cap)
+}
+
+// Capacity the size of the ring
+func (q *QueueT[T]) Capacity() uint32 {
+	return q.cap
+}
+
+// putAt 同 DefaultQueue.putAt，等该位置被上一轮读完后写入
+func (q *QueueT[T]) putAt(pos uint32, val T) {
+	cache := &q.carrier[pos&q.capMod]
+	for {
+		readID := cache.readID.Load()
+		writeID := cache.writeID.Load()
+		if pos == writeID && readID == writeID {
+			cache.value = val
+			cache.writeID.Add(q.cap)
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+// takeAt 同 DefaultQueue.takeAt，等该位置写入完成后取出并清空
+func (q *QueueT[T]) takeAt(pos uint32) (val T) {
+	cache := &q.carrier[pos&q.capMod]
+	for {
+		readID := cache.readID.Load()
+		writeID := cache.writeID.Load()
+		if pos == readID && readID+q.cap == writeID {
+			var zero T
+			val, cache.value = cache.value, zero
+			cache.readID.Add(q.cap)
+			return val
+		}
+		runtime.Gosched()
+	}
+}
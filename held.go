@@ -0,0 +1,68 @@
+package queue
+
+/*
+ @File : items.go
+ @Description: borrow the items in place, the slots are released after the consumer is done
+ @Time : 2026/10/15
+*/
+
+import "sync"
+
+// heldPool GetHeld 返回的切片复用，release 之后放回，放的是指针，Put 时不用再分配
+var heldPool = sync.Pool{
+	New: func() interface{} {
+		items := make([]interface{}, 0, 64)
+		return &items
+	},
+}
+
+// GetHeld take up to max items with one CAS and hold their slots until release is called,
+// the slots are cleared and can be reused by producers only after that.
+// Producers wait on these slots until release, so process quickly and never hold them for long.
+// It is NOT zero copy: items is a pooled slice holding copies of the interface values in the slots,
+// what it saves over Gets is the allocation of the slice, the objects the values point to are shared.
+// items is reused after release, DON'T keep it or any reference to it after release.
+// WithCopyOnGet is not applied, PutCallback callbacks are called in release.
+// items is empty and release is a no-op if the queue is empty or lock positions failed
+func (q *DefaultQueue) GetHeld(max int) (items []interface{}, release func()) {
+	if max <= 0 {
+		return nil, func() {}
+	}
+	if q.spill != nil && q.spill.len() > 0 {
+		q.refill()
+	}
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := q.posCount(read, write)
+	n := q.reserveGet(read, cnt, uint32(max))
+	if n == 0 {
+		q.yield()
+		return nil, func() {}
+	}
+
+	buf := heldPool.Get().(*[]interface{})
+	items = (*buf)[:0]
+	for i := uint32(0); i < n; i++ {
+		val := q.peekAt(read + 1 + i)
+		if val == tombstone {
+			val = nil
+		}
+		items = append(items, val)
+	}
+
+	var once sync.Once
+	return items, func() {
+		once.Do(func() {
+			// 槽在这里才清空，之前生产者一直等待这些位置，items 中的数据不会被覆盖
+			for i := uint32(0); i < n; i++ {
+				q.takeAt(read + 1 + i)
+			}
+			for i := range items {
+				items[i] = nil
+			}
+			*buf = items[:0] // append 可能换了更大的底层数组，放回的是最新的
+			heldPool.Put(buf)
+		})
+	}
+}
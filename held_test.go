@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetHeld(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(10))
+
+	items, release := q.GetHeld(4)
+	assertSeq(t, items, 0, 4)
+	// release 之前数据还在槽里，位置已经被占，其他消费者从后面开始取
+	for i := uint32(1); i <= 4; i++ {
+		if q.carrier[i&q.capMod].value != int(i-1) {
+			t.Fatalf("slot %d holds %v before release", i, q.carrier[i].value)
+		}
+	}
+	if val, _, _ := q.Get(); val != 4 {
+		t.Fatalf("get %v while held, want 4", val)
+	}
+	release()
+	for i := uint32(1); i <= 4; i++ {
+		if q.carrier[i&q.capMod].value != nil {
+			t.Fatalf("slot %d not cleared after release", i)
+		}
+	}
+	release() // 多次调用没有影响
+
+	items, release = q.GetHeld(100)
+	assertSeq(t, items, 5, 5)
+	release()
+	if q.Count() != 0 {
+		t.Fatalf("count %d after holding all", q.Count())
+	}
+	if items, release := q.GetHeld(4); len(items) != 0 {
+		t.Fatalf("held %v from an empty queue", items)
+	} else {
+		release()
+	}
+}
+
+func TestGetHeldHoldsSlotsUntilRelease(t *testing.T) {
+	q := newDefaultQueue(8)
+	n := q.PutSlice(ints(8))
+	items, release := q.GetHeld(n)
+	if len(items) != n {
+		t.Fatalf("held %d, want %d", len(items), n)
+	}
+
+	// 取走了位置，生产者可以占位，但要等 release 之后才能写入，items 中的数据不会被覆盖
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2; {
+			if ok, _ := q.Put(100 + i); ok {
+				i++
+			}
+		}
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assertSeq(t, items, 0, n)
+	release()
+	<-done
+	if vals := drainAll(t, q); len(vals) != 2 || vals[0] != 100 || vals[1] != 101 {
+		t.Fatalf("queue holds %v after release", vals)
+	}
+}
+
+func TestGetHeldAllocs(t *testing.T) {
+	q := newDefaultQueue(64)
+	vals := ints(32)
+	// 复用的是切片的指针，放回池子不分配，只剩 release 闭包以及它捕获的变量
+	allocs := testing.AllocsPerRun(100, func() {
+		q.Puts(vals)
+		_, release := q.GetHeld(32)
+		release()
+	})
+	if allocs > 3 {
+		t.Fatalf("%v allocs per GetHeld", allocs)
+	}
+}
+
+func BenchmarkGetHeld(b *testing.B) {
+	q := newDefaultQueue(1024)
+	vals := ints(64)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Puts(vals)
+		_, release := q.GetHeld(64)
+		release()
+	}
+}
@@ -0,0 +1,49 @@
+package queue
+
+/*
+ @File : latest.go
+ @Description: queue which keep only the most recent item, for state updates where only the newest matters
+ @Time : 2026/10/15
+*/
+
+import "go.uber.org/atomic"
+
+// LatestQueue hold at most one item, Put always replace it, the stale one is dropped,
+// Get take the latest one. Both are a single atomic swap, safe for any number of goroutines
+type LatestQueue struct {
+	v *atomic.Value // *latestBox，nil 表示空
+}
+
+// latestBox 包一层，这样 nil 也可以作为值放进去，和空区分开
+type latestBox struct {
+	val interface{}
+}
+
+// NewLatestQueue alloc an empty LatestQueue
+func NewLatestQueue() *LatestQueue {
+	return &LatestQueue{v: &atomic.Value{}}
+}
+
+// Put replace the stored item with val, never fail, count is always 1
+func (q *LatestQueue) Put(val interface{}) (ok bool, count uint32) {
+	q.v.Store(&latestBox{val: val})
+	return true, 1
+}
+
+// Get take the latest item, ok false if nothing was put since the last Get, count is always 0 after
+func (q *LatestQueue) Get() (val interface{}, ok bool, count uint32) {
+	// 存的总是 *latestBox，空的时候是 nil 的 *latestBox，atomic.Value 不能存 nil 接口
+	b, _ := q.v.Swap((*latestBox)(nil)).(*latestBox)
+	if b == nil {
+		return nil, false, 0
+	}
+	return b.val, true, 0
+}
+
+// Count 1 if there is an item, otherwise 0
+func (q *LatestQueue) Count() uint32 {
+	if b, _ := q.v.Load().(*latestBox); b == nil {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLatestQueueKeepsNewest(t *testing.T) {
+	q := NewLatestQueue()
+	if _, ok, _ := q.Get(); ok {
+		t.Fatal("got from a queue never put")
+	}
+	for i := 0; i < 100; i++ {
+		q.Put(i)
+	}
+	if q.Count() != 1 {
+		t.Fatalf("count %d after many puts", q.Count())
+	}
+	// 只剩最后一个，之前的都丢弃了
+	if val, ok, _ := q.Get(); !ok || val != 99 {
+		t.Fatalf("get %v %v, want 99", val, ok)
+	}
+	if _, ok, _ := q.Get(); ok || q.Count() != 0 {
+		t.Fatal("got again after the latest was consumed")
+	}
+	// nil 也是一个值，和空区分开
+	q.Put(nil)
+	if val, ok, _ := q.Get(); !ok || val != nil {
+		t.Fatalf("get %v %v after put nil", val, ok)
+	}
+}
+
+func TestLatestQueueConcurrentNeverStale(t *testing.T) {
+	q := NewLatestQueue()
+	const producers, per = 4, 20000
+	var wg sync.WaitGroup
+	var done int32
+	// 每个生产者的值递增，消费者看到的同一个生产者的值不能倒退
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 1; i <= per; i++ {
+				q.Put([2]int{p, i})
+			}
+		}(p)
+	}
+	go func() {
+		wg.Wait()
+		atomic.StoreInt32(&done, 1)
+	}()
+
+	last := make([]int, producers)
+	for atomic.LoadInt32(&done) == 0 {
+		val, ok, _ := q.Get()
+		if !ok {
+			continue
+		}
+		v := val.([2]int)
+		if v[1] <= last[v[0]] {
+			t.Fatalf("producer %d: got %d after %d", v[0], v[1], last[v[0]])
+		}
+		last[v[0]] = v[1]
+	}
+	// 全部放完后剩下的是某个生产者的最后一个值
+	if val, ok, _ := q.Get(); ok {
+		if v := val.([2]int); v[1] != per {
+			t.Fatalf("latest after all puts is %v, want a last value %d", v, per)
+		}
+	}
+}
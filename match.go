@@ -0,0 +1,103 @@
+package queue
+
+/*
+ @File : match.go
+ @Description: selective get and move, take the items matching a predicate and keep the others in place
+ @Time : 2026/10/15
+*/
+
+// GetMatch take the first item from the head for which match return true, the items before it stay in order.
+// The matched item is moved to the head by shifting the earlier ones back one slot, then taken as Get does,
+// so it costs O(n) and is NOT lock free: only one consumer may run at the same time,
+// no Get/Gets/GetMatch from other goroutines, producers can still Put concurrently.
+// The scan stops at the first slot which is not committed yet, ok false if no match before it
+func (q *DefaultQueue) GetMatch(match func(interface{}) bool) (val interface{}, ok bool) {
+	read := q.read.Load()
+	write := q.write.Load()
+	cnt := q.posCount(read, write)
+
+	head := read + 1
+	for k := uint32(0); k < cnt; k++ {
+		pos := head + k
+		if !q.committed(pos) {
+			return nil, false
+		}
+		e := q.carrier[pos&q.capMod].entry
+		if e.value == nil || e.value == tombstone || !match(e.value) {
+			continue
+		}
+		// 只有一个消费者，已经提交的槽只有自己会动，可以直接搬动数据：前面的往后挪一格，匹配的放到队头
+		for j := k; j > 0; j-- {
+			q.carrier[(head+j)&q.capMod].entry = q.carrier[(head+j-1)&q.capMod].entry
+		}
+		q.carrier[head&q.capMod].entry = e
+		if !q.casRead(read, head) {
+			return nil, false // 违反了单消费者的约定
+		}
+		e = q.takeAt(head)
+		return e.value, e.value != nil
+	}
+	return nil, false
+}
+
+// Partition move the items for which match return true into dst in order, the others stay in q in order,
+// return how many moved. When dst is full (or closed), the moving stops there and the rest matched items stay in q,
+// compare the result with the expected number to find it out.
+// Like GetMatch the items are shuffled in place, so the queue must be quiesced: no Put/Get on q from other goroutines.
+// The callback of PutCallback is called for the moved items, the metadata of them is not carried to dst
+func (q *DefaultQueue) Partition(dst Queue, match func(interface{}) bool) int {
+	read := q.read.Load()
+	write := q.write.Load()
+	cnt := q.posCount(read, write)
+
+	head := read + 1
+	moved := make([]bool, cnt)
+	n := uint32(0)
+	for k := uint32(0); k < cnt; k++ {
+		if !q.committed(head + k) {
+			cnt = k // 约定是静止的，遇到没写完的就只处理前面的
+			break
+		}
+		e := q.carrier[(head+k)&q.capMod].entry
+		if e.value == nil || e.value == tombstone || !match(e.value) {
+			continue
+		}
+		if !hasSpace(dst) || !putRetry(dst, e.value) {
+			break
+		}
+		moved[k] = true
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+
+	// 从队尾往前，把留下的往后挪，紧挨着排在末尾，移走的回调之后再调用
+	var dones []func()
+	to := head + cnt - 1
+	for k := int(cnt) - 1; k >= 0; k-- {
+		cache := &q.carrier[(head+uint32(k))&q.capMod]
+		if moved[k] {
+			if done := cache.done(); done != nil {
+				dones = append(dones, done)
+			}
+			continue
+		}
+		q.carrier[to&q.capMod].entry = cache.entry
+		to--
+	}
+	// 空出来的队头清空后按 Get 的方式释放
+	for pos := head; pos != head+n; pos++ {
+		q.carrier[pos&q.capMod].entry = entry{}
+	}
+	if !q.casRead(read, read+n) {
+		panic(q.tag() + ": Partition on a queue which is not quiesced")
+	}
+	for pos := head; pos != head+n; pos++ {
+		q.takeAt(pos)
+	}
+	for i := len(dones) - 1; i >= 0; i-- {
+		dones[i]()
+	}
+	return int(n)
+}
@@ -0,0 +1,131 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPartitionRejectingDst(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(5))
+	dst := NewAdmissionQueue(16, 0.1)
+
+	// dst 拒绝后停下来，剩下的留在原队列
+	if n := q.Partition(dst, func(interface{}) bool { return true }); n != 2 {
+		t.Fatalf("moved %d, want 2", n)
+	}
+	assertSeq(t, drainAll(t, q), 2, 3)
+}
+
+func TestGetMatch(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice([]interface{}{1, 3, 4, 5, 6, 7})
+	even := func(v interface{}) bool { return v.(int)%2 == 0 }
+
+	// 只取走第一个匹配的，前面不匹配的留在原位
+	if val, ok := q.GetMatch(even); !ok || val != 4 {
+		t.Fatalf("got %v %v, want 4", val, ok)
+	}
+	if val, ok := q.GetMatch(even); !ok || val != 6 {
+		t.Fatalf("got %v %v, want 6", val, ok)
+	}
+	if val, ok := q.GetMatch(even); ok {
+		t.Fatalf("got %v with no even left", val)
+	}
+	got := drainAll(t, q)
+	want := []interface{}{1, 3, 5, 7}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("rest %v, want %v in order", got, want)
+	}
+}
+
+func TestGetMatchHeadAndWrap(t *testing.T) {
+	q := newDefaultQueue(8)
+	// 先推进位置，让数据跨过环的末尾
+	for i := 0; i < 5; i++ {
+		q.Put(-1)
+		q.Get()
+	}
+	q.PutSlice(ints(6))
+	if val, ok := q.GetMatch(func(v interface{}) bool { return v == 0 }); !ok || val != 0 {
+		t.Fatalf("got %v %v, want the head 0", val, ok)
+	}
+	if val, ok := q.GetMatch(func(v interface{}) bool { return v == 4 }); !ok || val != 4 {
+		t.Fatalf("got %v %v, want 4", val, ok)
+	}
+	got := drainAll(t, q)
+	if fmt.Sprint(got) != fmt.Sprint([]interface{}{1, 2, 3, 5}) {
+		t.Fatalf("rest %v, want [1 2 3 5]", got)
+	}
+	if _, ok := q.GetMatch(func(interface{}) bool { return true }); ok {
+		t.Fatal("matched on an empty queue")
+	}
+}
+
+func TestPartition(t *testing.T) {
+	q := newDefaultQueue(16)
+	// 跨过环的末尾
+	advanceTo(q, 12)
+	q.PutSlice(ints(10))
+	dst := newDefaultQueue(16)
+	even := func(v interface{}) bool { return v.(int)%2 == 0 }
+
+	if n := q.Partition(dst, even); n != 5 {
+		t.Fatalf("moved %d, want 5", n)
+	}
+	// 两边都保持原来的顺序
+	if got := drainAll(t, dst); fmt.Sprint(got) != "[0 2 4 6 8]" {
+		t.Fatalf("dst %v, want [0 2 4 6 8]", got)
+	}
+	if got := drainAll(t, q); fmt.Sprint(got) != "[1 3 5 7 9]" {
+		t.Fatalf("source %v, want [1 3 5 7 9]", got)
+	}
+
+	// 没有匹配的不动
+	q.PutSlice([]interface{}{1, 3})
+	if n := q.Partition(dst, even); n != 0 || dst.Count() != 0 {
+		t.Fatalf("moved %d with no match", n)
+	}
+	if got := drainAll(t, q); fmt.Sprint(got) != "[1 3]" {
+		t.Fatalf("source %v, want [1 3]", got)
+	}
+	if n := q.Partition(dst, even); n != 0 {
+		t.Fatalf("moved %d from empty queue", n)
+	}
+}
+
+func TestPartitionDstFull(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(10))
+	dst := newDefaultQueue(8)
+	dst.PutSlice([]interface{}{-1, -2, -3, -4}) // 只剩 2 个空间
+
+	// dst 满了就停下，剩下匹配的留在原队列
+	if n := q.Partition(dst, func(v interface{}) bool { return v.(int)%2 == 0 }); n != 2 {
+		t.Fatalf("moved %d, want 2", n)
+	}
+	if got := drainAll(t, dst); fmt.Sprint(got) != "[-1 -2 -3 -4 0 2]" {
+		t.Fatalf("dst %v", got)
+	}
+	if got := drainAll(t, q); fmt.Sprint(got) != "[1 3 4 5 6 7 8 9]" {
+		t.Fatalf("source %v, want [1 3 4 5 6 7 8 9]", got)
+	}
+}
+
+func TestPartitionCallbacks(t *testing.T) {
+	q := newDefaultQueue(16)
+	var called []int
+	for i := 0; i < 4; i++ {
+		i := i
+		q.PutCallback(i, func() { called = append(called, i) })
+	}
+	// 移走的按顺序回调，留下的取出时才回调
+	q.Partition(newDefaultQueue(16), func(v interface{}) bool { return v.(int) >= 2 })
+	if fmt.Sprint(called) != "[2 3]" {
+		t.Fatalf("called %v after partition, want [2 3]", called)
+	}
+	drainAll(t, q)
+	if fmt.Sprint(called) != "[2 3 0 1]" {
+		t.Fatalf("called %v after drain, want [2 3 0 1]", called)
+	}
+}
@@ -0,0 +1,44 @@
+package queue
+
+/*
+ @File : memory.go
+ @Description: estimate the memory footprint of the queue for capacity planning
+ @Time : 2026/10/15
+*/
+
+import (
+	"unsafe"
+
+	"go.uber.org/atomic"
+)
+
+// idAllocBytes 单独分配的 atomic.Uint32 实际占用的内存，小对象按 8 字节对齐分配
+const idAllocBytes = 8
+
+// queueAtomics newQueueOn 中为队列本身分配的 atomic 对象个数，write、read、closed 等，由 TestQueueAtomics 核对
+const queueAtomics = 18
+
+// MemoryBytes estimate the memory held by the queue: the carrier, the writeID/readID of each slot,
+// and the struct itself. The values in queue, the callbacks and the spilled items are not counted,
+// the result depends only on cap and the options, it's linear in cap
+func (q *DefaultQueue) MemoryBytes() uint64 {
+	idBytes := uint64(idAllocBytes)
+	switch {
+	case q.idLines > 1:
+		idBytes = uint64(q.idLines) * cacheLine
+	case q.padded || q.idLines == 1:
+		idBytes = cacheLine
+	}
+
+	perSlot := uint64(unsafe.Sizeof(slot{})) + 2*idBytes
+	if q.callers != nil {
+		perSlot += uint64(unsafe.Sizeof(atomic.String{}))
+	}
+	return uint64(q.cap)*perSlot + uint64(unsafe.Sizeof(*q)) + queueAtomics*idAllocBytes
+}
+
+// MemoryBytes the same as DefaultQueue.MemoryBytes, the slot holds T and the IDs inline,
+// so it grows with the size of T
+func (q *QueueT[T]) MemoryBytes() uint64 {
+	return uint64(q.cap)*uint64(unsafe.Sizeof(slotT[T]{})) + uint64(unsafe.Sizeof(*q)) + 2*idAllocBytes
+}
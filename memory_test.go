@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestMemoryBytesLinear(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithPaddedSlots()},
+		{WithMinLinesPerSlot(4)},
+		{WithCallerTracking()},
+	} {
+		m := func(c uint32) uint64 { return newDefaultQueue(c, opts...).MemoryBytes() }
+		// 每多一倍的槽，增加的内存一样，没有和 cap 无关的变化
+		d1, d2 := m(2048)-m(1024), m(4096)-m(2048)
+		if d1 == 0 || d2 != 2*d1 {
+			t.Fatalf("footprint not linear in cap: +%d for 1024 slots, +%d for 2048", d1, d2)
+		}
+	}
+	plain, padded := newDefaultQueue(1024), newDefaultQueue(1024, WithPaddedSlots())
+	if padded.MemoryBytes() <= plain.MemoryBytes() {
+		t.Fatal("padded slots not counted")
+	}
+
+	small, big := NewQueueT[int64](1024), NewQueueT[[64]byte](1024)
+	if d := big.MemoryBytes() - small.MemoryBytes(); d != 1024*56 {
+		t.Fatalf("typed footprint differs by %d for 56 more bytes of T per slot", d)
+	}
+}
+
+// heapGrowth new 之后堆上增加的字节数
+func heapGrowth(new func() interface{}) (uint64, interface{}) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	v := new()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	return after.HeapAlloc - before.HeapAlloc, v
+}
+
+func TestMemoryBytesMatchesHeap(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector adds to the allocations")
+	}
+	for _, opts := range [][]Option{nil, {WithPaddedSlots()}} {
+		grown, v := heapGrowth(func() interface{} { return newDefaultQueue(1<<16, opts...) })
+		est := v.(*DefaultQueue).MemoryBytes()
+		// 估算值和实际分配的差别在 10% 以内
+		if grown < est*9/10 || grown > est*11/10 {
+			t.Fatalf("estimated %d bytes, heap grew %d", est, grown)
+		}
+		runtime.KeepAlive(v)
+	}
+	grown, v := heapGrowth(func() interface{} { return NewQueueT[[24]byte](1 << 16) })
+	if est := v.(*QueueT[[24]byte]).MemoryBytes(); grown < est*9/10 || grown > est*11/10 {
+		t.Fatalf("typed: estimated %d bytes, heap grew %d", est, grown)
+	}
+	runtime.KeepAlive(v)
+}
+
+// countAtomics 数出 v 中不为空的 *atomic.* 指针字段，包括内嵌的结构体（比如 metrics）中的
+func countAtomics(v reflect.Value) int {
+	n := 0
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.Struct:
+			n += countAtomics(f)
+		case f.Kind() == reflect.Ptr && !f.IsNil() && f.Type().Elem().PkgPath() == "go.uber.org/atomic":
+			n++
+		}
+	}
+	return n
+}
+
+func TestQueueAtomics(t *testing.T) {
+	// queueAtomics 是手数的，newQueueOn 多分配或者少分配了 atomic 对象时要同步修改
+	q := newDefaultQueue(8)
+	if n := countAtomics(reflect.ValueOf(q).Elem()); n != queueAtomics {
+		t.Fatalf("newQueueOn allocs %d atomics, queueAtomics is %d", n, queueAtomics)
+	}
+}
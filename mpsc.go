@@ -0,0 +1,24 @@
+package queue
+
+/*
+ @File : mpsc.go
+ @Description: bounded queue for many producers and only one consumer,
+               the consumer side move read without CAS
+ @Time : 2026/10/15
+*/
+
+// MPSCQueue multi producer single consumer queue,
+// Put is the same as DefaultQueue, can be called from many goroutines,
+// Get (and other consume methods) must be called from ONLY ONE goroutine at the same time,
+// otherwise items will be lost or duplicated
+type MPSCQueue struct {
+	*DefaultQueue
+}
+
+// NewMPSCQueue alloc a MPSCQueue, see MPSCQueue for the single consumer requirement
+func NewMPSCQueue(cap uint32, opts ...Option) Queue {
+	q := &MPSCQueue{DefaultQueue: newDefaultQueue(cap, opts...)}
+	q.singleConsumer = true // 消费者移动 read 不需要 CAS
+	q.registerAs(q)
+	return q
+}
@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+)
+
+// produceConsume producers 个生产者各放入 perProducer 个数据，consumers 个消费者取出，
+// 检查每个数据都取到且只取到一次，同一个生产者的数据按顺序取出（单消费者时）
+func produceConsume(t testing.TB, q Queue, producers, consumers, perProducer int) {
+	total := producers * perProducer
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; {
+				if ok, _ := q.Put(p*perProducer + i); ok {
+					i++
+				}
+			}
+		}(p)
+	}
+
+	seen := make([]int32, total)
+	var mu sync.Mutex
+	left := total
+	var cwg sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			last := make([]int, producers) // 单消费者时检查每个生产者的顺序
+			for i := range last {
+				last[i] = -1
+			}
+			for {
+				mu.Lock()
+				done := left == 0
+				mu.Unlock()
+				if done {
+					return
+				}
+				val, ok, _ := q.Get()
+				if !ok {
+					continue
+				}
+				v := val.(int)
+				if consumers == 1 {
+					p := v / perProducer
+					if v <= last[p] {
+						t.Errorf("producer %d: got %d after %d", p, v, last[p])
+					}
+					last[p] = v
+				}
+				mu.Lock()
+				seen[v]++
+				left--
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	cwg.Wait()
+	for v, n := range seen {
+		if n != 1 {
+			t.Fatalf("item %d got %d times", v, n)
+		}
+	}
+}
+
+func TestMPSCNoLoss(t *testing.T) {
+	produceConsume(t, NewMPSCQueue(64), 8, 1, 2000)
+}
+
+func BenchmarkMPSC(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		produceConsume(b, NewMPSCQueue(1024), 8, 1, 1000)
+	}
+}
+
+func BenchmarkMPSCDefault(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		produceConsume(b, NewQueue(1024), 8, 1, 1000)
+	}
+}
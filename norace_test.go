@@ -0,0 +1,6 @@
+//go:build !race
+
+package queue
+
+// raceEnabled 开启了 -race，检测器会改变分配的大小
+const raceEnabled = false
@@ -0,0 +1,235 @@
+package queue
+
+/*
+ @File : option.go
+ @Description: optional config for DefaultQueue, pass to NewQueue
+ @Time : 2026/10/15
+*/
+
+import (
+	"sync"
+	"time"
+)
+
+// Option change the DefaultQueue when NewQueue
+type Option func(q *DefaultQueue)
+
+// WithRegistry register the queue into the package level registry by name,
+// unregister when Close, see Registry
+func WithRegistry(name string) Option {
+	return func(q *DefaultQueue) {
+		q.name = name
+	}
+}
+
+// WithID set an identifier of the queue for logs and metrics, shown by Info, String, the panics of WithStrictChecks
+// and debughttp, unlike WithRegistry it doesn't register the queue and needn't be unique
+func WithID(id string) Option {
+	return func(q *DefaultQueue) {
+		q.id = id
+	}
+}
+
+// WithValuePool set a pool for recycling the value wrappers, see AcquireValue and ReleaseValue
+func WithValuePool(pool *sync.Pool) Option {
+	return func(q *DefaultQueue) {
+		q.pool = pool
+	}
+}
+
+// WithFullCallback set fn which is called when the queue stays full,
+// that is FullStreak times of Put failed because of full without any success Put between them,
+// and only call once for each streak, so the application can shed load or alert
+func WithFullCallback(fn func(count uint32)) Option {
+	return func(q *DefaultQueue) {
+		q.fullCallback = fn
+	}
+}
+
+// WithEmptyCallback set fn which is called when the queue stays empty,
+// that is EmptyStreak times of Get found nothing without any success Get between them,
+// and only call once for each streak, so the application can park some consumers
+func WithEmptyCallback(fn func()) Option {
+	return func(q *DefaultQueue) {
+		q.emptyCallback = fn
+	}
+}
+
+// WithLatencyHook set fn which receive the cost time of Put/Get, op is "put" or "get",
+// the time include the spin waiting for the slot.
+// Timing cost a time.Now pair and a shared atomic counter on each sampled operation,
+// use WithLatencySampling to time only a part of them
+func WithLatencyHook(fn func(op string, d time.Duration)) Option {
+	return func(q *DefaultQueue) {
+		q.latencyHook = fn
+	}
+}
+
+// WithLatencySampling only time 1 of every n Put/Get for WithLatencyHook, default 1 means all
+func WithLatencySampling(n uint32) Option {
+	return func(q *DefaultQueue) {
+		if n > 0 {
+			q.latencyEvery = n
+		}
+	}
+}
+
+// WithSpillover write the items to files in dir when the queue is full instead of fail,
+// and load them back in order when Get makes room, so a large burst is not dropped.
+// The items are encoded by gob, only gob-encodable values are supported,
+// and the concrete types must be registered by gob.Register.
+// The disk side is protected by a mutex, not lock free, and only Put/Get/Gets go through it,
+// Puts treat the queue as full while there are items on disk
+func WithSpillover(dir string) Option {
+	return func(q *DefaultQueue) {
+		q.spill = newSpillStore(dir)
+	}
+}
+
+// WithTimestamp record the time when each item is put, see EvictOlderThan.
+// The time is kept out of the slot with the other per-item extras, so each Put allocates a little
+func WithTimestamp() Option {
+	return func(q *DefaultQueue) {
+		q.stamp = true
+	}
+}
+
+// WithRateTracking record the average interval between items taken out,
+// cost a time.Now on each item, used by PutAdaptive and PutOrWaitHint
+func WithRateTracking() Option {
+	return func(q *DefaultQueue) {
+		q.rate = true
+	}
+}
+
+// WithCopyOnGet apply copyFn to each item taken out and return the copy,
+// the slot is cleared before copy, so the consumer never shares an object with the queue
+func WithCopyOnGet(copyFn func(interface{}) interface{}) Option {
+	return func(q *DefaultQueue) {
+		q.copyFn = copyFn
+	}
+}
+
+// WithPaddedSlots let the writeID and readID of each slot take a whole cache line,
+// so the producers and consumers working on the neighbouring slots don't share cache lines.
+// It helps under high concurrency with many goroutines on both sides,
+// the cost is 2 cache lines (128 bytes) per slot instead of 16 bytes
+func WithPaddedSlots() Option {
+	return func(q *DefaultQueue) {
+		q.padded = true
+	}
+}
+
+// WithMinLinesPerSlot let the writeID and readID of each slot take lines cache lines, 1 is the same as WithPaddedSlots.
+// For the tiny queues (cap near MinCap) with several goroutines on both sides, all the slots are hot,
+// more lines keep the adjacent cache line prefetch of the CPU from dragging the neighbouring slots in.
+// The cost is 2*lines cache lines per slot, it doesn't help the big queues or few goroutines,
+// and doesn't help without several CPUs, measure with BenchmarkMinLinesPerSlot on the target machine first
+func WithMinLinesPerSlot(lines int) Option {
+	return func(q *DefaultQueue) {
+		q.idLines = lines
+	}
+}
+
+// WithCallerTracking record who reserved each slot, the function and line of the caller out of this package,
+// or the label of ReserveLabel, see StalledSlots. It costs a runtime.Callers on each reservation, for debugging only
+func WithCallerTracking() Option {
+	return func(q *DefaultQueue) {
+		q.tracking = true
+	}
+}
+
+// WithAutoFallback switch Put/Get into mutex mode after threshold CAS failures in a row,
+// one goroutine works at a time instead of all of them spinning on CAS, and switch back
+// after a while without waiters. Under pathological contention it trades latency for much less CPU.
+// The same lock-free protocol runs under the mutex, so FIFO and no loss hold in both modes and during switching
+func WithAutoFallback(threshold uint32) Option {
+	return func(q *DefaultQueue) {
+		q.fb = newFallback(threshold)
+	}
+}
+
+// WithZeroOnReserve clear the slot as soon as the reserved position is free to write, before the new value is written.
+// Get already clears the slot when taking the item, this is defense in depth for the security sensitive data,
+// so no value outlives its Get in the slot whatever path touched it
+func WithZeroOnReserve() Option {
+	return func(q *DefaultQueue) {
+		q.zeroOnReserve = true
+	}
+}
+
+// WithStrictChecks verify the usage in Put/Get and panic with a descriptive message on violation,
+// such as Reset during Put/Get, concurrent consumers on MPSCQueue, or a slot whose IDs are broken.
+// For development and tests only, it costs a defer and some atomic loads on each operation
+func WithStrictChecks() Option {
+	return func(q *DefaultQueue) {
+		q.checks = true
+	}
+}
+
+// WithCarrierHint with reserve true, write the whole carrier once when created,
+// so the memory is committed and resident before the first Put/Get, see Warm.
+// Go can't pin the heap memory, this is the most it can do
+func WithCarrierHint(reserve bool) Option {
+	return func(q *DefaultQueue) {
+		q.prefault = reserve
+	}
+}
+
+// WithStats collect the runtime statistics of the queue, see AvgGetInterval and Stats,
+// it includes WithRateTracking
+func WithStats() Option {
+	return func(q *DefaultQueue) {
+		q.stats = true
+		q.rate = true
+	}
+}
+
+// WithSpaceAvailable set fn called by Get when it frees a slot of a full queue,
+// only once for each full to not-full edge, for waking up the waiting producers.
+// fn is called in the Get goroutine, it should not block
+func WithSpaceAvailable(fn func()) Option {
+	return func(q *DefaultQueue) {
+		q.spaceCallback = fn
+	}
+}
+
+// WithDropAfter let Get give up the head position after n failed spins waiting for the producer to write,
+// the position is skipped as an empty item (Get return ok false) and the consumer moves on,
+// for the real-time feeds which prefer losing data to waiting a stalled producer.
+// The value of the stalled producer is dropped silently when it writes at last,
+// so the items may be lost, and Token.Committed of a dropped position return true
+func WithDropAfter(n uint32) Option {
+	return func(q *DefaultQueue) {
+		q.dropAfter = n
+	}
+}
+
+// WithStrictCapacity make NewQueueE reject the cap which is not 2's power or less than MinCap,
+// instead of rounding it up silently, which may alloc nearly 2x memory than expected.
+// NewQueue ignores it
+func WithStrictCapacity() Option {
+	return func(q *DefaultQueue) {
+		q.strict = true
+	}
+}
+
+// WithNoYieldOnFail make Put/Get and the batch versions return immediately when full, empty or lock slot failed,
+// without runtime.Gosched before return, for the callers which fail fast and do something else.
+// The caller retrying in a tight loop should yield by itself
+func WithNoYieldOnFail() Option {
+	return func(q *DefaultQueue) {
+		q.noYield = true
+	}
+}
+
+// WithUncommittedPolicy choose what Get does when the head is reserved by a producer but not written yet:
+// SpinOnUncommitted reserve the head and wait for the producer, the default;
+// EmptyOnUncommitted return ok false and count 0 as if empty;
+// PendingOnUncommitted return ok false with the real count, so count > 0 with ok false means pending.
+// The head is checked before reserving it, the item is not lost. Only affects Get, see GetReady
+func WithUncommittedPolicy(policy UncommittedPolicy) Option {
+	return func(q *DefaultQueue) {
+		q.uncommitted = policy
+	}
+}
@@ -0,0 +1,208 @@
+package queue
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"unsafe"
+)
+
+// lineOf 地址所在的 cache line
+func lineOf(p unsafe.Pointer) uintptr {
+	return uintptr(p) / cacheLine
+}
+
+func TestPaddedSlotsOwnCacheLines(t *testing.T) {
+	q := newDefaultQueue(64, WithPaddedSlots())
+	lines := map[uintptr]int{}
+	for i := range q.carrier {
+		lines[lineOf(unsafe.Pointer(q.carrier[i].writeID))]++
+		lines[lineOf(unsafe.Pointer(q.carrier[i].readID))]++
+	}
+	// 每个 writeID/readID 独占一个 cache line，互相不共享
+	if len(lines) != 2*len(q.carrier) {
+		t.Fatalf("%d ids on %d cache lines", 2*len(q.carrier), len(lines))
+	}
+
+	for round := 0; round < 5; round++ {
+		n := q.PutSlice(ints(100))
+		assertSeq(t, drainAll(t, q), 0, n)
+	}
+	produceConsume(t, q, 4, 4, 5000)
+}
+
+// 4P/4C，cap 1024，go test -bench PaddedSlots -cpu 1,8，在只有 1 个 CPU 的机器上测得：
+//
+//	BenchmarkPaddedSlots/plain      90.35 ns/op
+//	BenchmarkPaddedSlots/plain-8    2251 ns/op
+//	BenchmarkPaddedSlots/padded     90.52 ns/op
+//	BenchmarkPaddedSlots/padded-8   1887 ns/op
+//
+// 1 个 CPU 上没有 cache line 的争用，-8 只是让 8 个 goroutine 轮流占用 CPU，差别主要来自调度，
+// 多核机器上的收益需要重新测。内存从每个槽 16 字节变成 128 字节，所以没有作为默认，见 WithPaddedSlots
+func BenchmarkPaddedSlots(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"padded", []Option{WithPaddedSlots()}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			q := newDefaultQueue(1024, c.opts...)
+			benchPair(b,
+				func(v interface{}) bool { ok, _ := q.Put(v); return ok },
+				func() bool { _, ok, _ := q.Get(); return ok })
+		})
+	}
+}
+
+func TestNoYieldOnFail(t *testing.T) {
+	q := newDefaultQueue(8, WithNoYieldOnFail())
+	if _, ok, _ := q.Get(); ok {
+		t.Fatal("get on empty succeeded")
+	}
+	n := q.PutSlice(ints(10))
+	if ok, cnt := q.Put(-1); ok || cnt != uint32(n) {
+		t.Fatalf("put on full returned %v count %d", ok, cnt)
+	}
+	assertSeq(t, drainAll(t, q), 0, n)
+	// 不让出 CPU 的并发自旋在单核上只能靠抢占推进，这里只验证多轮回绕后仍然正确
+	for round := 0; round < 20; round++ {
+		n := q.PutSlice(ints(round%8 + 1))
+		assertSeq(t, drainAll(t, q), 0, n)
+	}
+}
+
+// BenchmarkFullPut 一直满的队列上 Put 失败返回的耗时，默认会 Gosched 再返回，WithNoYieldOnFail 直接返回
+func BenchmarkFullPut(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		opts []Option
+	}{
+		{"yield", nil},
+		{"noYield", []Option{WithNoYieldOnFail()}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			q := newDefaultQueue(8, c.opts...)
+			q.PutSlice(ints(8))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if ok, _ := q.Put(i); ok {
+					b.Fatal("put on full succeeded")
+				}
+			}
+		})
+	}
+}
+
+func TestMinLinesPerSlotSpacing(t *testing.T) {
+	for _, lines := range []int{1, 2, 4} {
+		q := newDefaultQueue(8, WithMinLinesPerSlot(lines))
+		var addrs []uintptr
+		for i := range q.carrier {
+			addrs = append(addrs, uintptr(unsafe.Pointer(q.carrier[i].writeID)), uintptr(unsafe.Pointer(q.carrier[i].readID)))
+		}
+		sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+		// 每个 ID 后面至少留出 lines 个 cache line，不会和其他 ID 挨着
+		for i := 1; i < len(addrs); i++ {
+			if gap := addrs[i] - addrs[i-1]; gap < uintptr(lines)*cacheLine {
+				t.Fatalf("lines %d: two ids %d bytes apart", lines, gap)
+			}
+		}
+	}
+}
+
+func TestMinLinesPerSlotCorrectness(t *testing.T) {
+	for _, lines := range []int{1, 2, 4} {
+		q := newDefaultQueue(8, WithMinLinesPerSlot(lines))
+		for round := 0; round < 10; round++ {
+			n := q.PutSlice(ints(10))
+			if n != 6 {
+				t.Fatalf("lines %d: put %d, want 6", lines, n)
+			}
+			assertSeq(t, drainAll(t, q), 0, n)
+		}
+		q.Reset()
+		produceConsume(t, q, 4, 4, 2000)
+		produceConsume(t, newDefaultQueue(8, WithMinLinesPerSlot(lines)), 4, 1, 2000)
+	}
+}
+
+// cap 8，4P/4C，go test -bench MinLinesPerSlot -cpu 1,8，在只有 1 个 CPU 的机器上测得：
+//
+//	BenchmarkMinLinesPerSlot/lines0     222.2 ns/op
+//	BenchmarkMinLinesPerSlot/lines0-8   1220 ns/op
+//	BenchmarkMinLinesPerSlot/lines1     276.8 ns/op
+//	BenchmarkMinLinesPerSlot/lines1-8   3480 ns/op
+//	BenchmarkMinLinesPerSlot/lines2     250.7 ns/op
+//	BenchmarkMinLinesPerSlot/lines2-8   1540 ns/op
+//	BenchmarkMinLinesPerSlot/lines4     224.3 ns/op
+//	BenchmarkMinLinesPerSlot/lines4-8   2176 ns/op
+//
+// 1 个 CPU 上没有 cache line 的争用，差别来自分配和调度，没有收益，多核机器上的收益需要重新测
+func BenchmarkMinLinesPerSlot(b *testing.B) {
+	for _, lines := range []int{0, 1, 2, 4} {
+		b.Run(fmt.Sprint("lines", lines), func(b *testing.B) {
+			q := newDefaultQueue(8, WithMinLinesPerSlot(lines))
+			benchPair(b,
+				func(v interface{}) bool { ok, _ := q.Put(v); return ok },
+				func() bool { _, ok, _ := q.Get(); return ok })
+		})
+	}
+}
+
+func TestPaddedUint32Size(t *testing.T) {
+	if s := unsafe.Sizeof(paddedUint32{}); s != cacheLine {
+		t.Fatalf("paddedUint32 is %d bytes, want one cache line of %d", s, cacheLine)
+	}
+}
+
+func TestZeroOnReserveCycle(t *testing.T) {
+	q := newDefaultQueue(8, WithZeroOnReserve())
+	// 跑几轮让每个槽都被重复使用
+	for i := 0; i < 3*int(q.cap); i++ {
+		secret := fmt.Sprintf("secret-%d", i)
+		q.Put(secret)
+		if val, ok, _ := q.Get(); !ok || val != secret {
+			t.Fatalf("get %v %v, want %s", val, ok, secret)
+		}
+		token, ok := q.Reserve()
+		if !ok {
+			t.Fatal("reserve failed")
+		}
+		// 取走后到下一次写入之间，槽里没有旧的数据
+		for j, s := range q.carrier {
+			if s.value != nil {
+				t.Fatalf("round %d: slot %d holds %v after get and reserve", i, j, s.value)
+			}
+		}
+		token.Commit(i)
+		if val, _, _ := q.Get(); val != i {
+			t.Fatalf("round %d: got %v from the reserved slot", i, val)
+		}
+	}
+}
+
+func TestWithID(t *testing.T) {
+	q := newDefaultQueue(8, WithID("orders"))
+	q.Put(1)
+	if q.ID() != "orders" {
+		t.Fatalf("id %q", q.ID())
+	}
+	if info, want := q.Info(), "id: orders, cap: 8, count: 1, closed: false"; info != want {
+		t.Fatalf("info %q, want %q", info, want)
+	}
+	if s, want := q.String(), "queue{id: orders, cap: 8, count: 1, closed: false}"; s != want {
+		t.Fatalf("string %q, want %q", s, want)
+	}
+	if s := fmt.Sprint(q); s != q.String() {
+		t.Fatalf("%%v printed %q", s)
+	}
+
+	// 没有设置时不显示
+	q = newDefaultQueue(8)
+	if q.ID() != "" || q.Info() != "cap: 8, count: 0, closed: false" {
+		t.Fatalf("id %q info %q without WithID", q.ID(), q.Info())
+	}
+}
@@ -0,0 +1,96 @@
+package queue
+
+/*
+ @File : packed.go
+ @Description: prototype queue which pack read and write positions into one 64 bit word,
+               one Load get both of them, so the count is always exact
+ @Time : 2026/10/15
+*/
+
+import (
+	"runtime"
+
+	"go.uber.org/atomic"
+)
+
+// PackedQueue keep read in the high 32 bits and write in the low 32 bits of one atomic word,
+// the count is a consistent snapshot without the two loads window of DefaultQueue,
+// but producers and consumers CAS the same word, so they contend with each other.
+// A prototype for comparing with DefaultQueue, only the basic methods are supported
+type PackedQueue struct {
+	ring *DefaultQueue  // 只使用其中的槽，read/write 不使用
+	pos  *atomic.Uint64 // 高 32 位 read，低 32 位 write
+}
+
+// NewPackedQueue alloc a PackedQueue, cap is rounded the same as NewQueue
+func NewPackedQueue(cap uint32) *PackedQueue {
+	return &PackedQueue{
+		ring: newDefaultQueue(cap),
+		pos:  atomic.NewUint64(0),
+	}
+}
+
+// Put May failed if lock slot failed or full, the same as DefaultQueue.Put
+func (q *PackedQueue) Put(val interface{}) (ok bool, count uint32) {
+	pos := q.pos.Load()
+	read, write := unpackPos(pos)
+
+	cnt := write - read
+	if cnt >= q.ring.capMod-1 {
+		runtime.Gosched()
+		return false, cnt
+	}
+
+	posNext := write + 1
+	if !q.pos.CAS(pos, packPos(read, posNext)) {
+		runtime.Gosched()
+		return false, cnt
+	}
+
+	q.ring.putAt(posNext, entry{value: val})
+	return true, cnt + 1
+}
+
+// Get May failed if lock slot failed or empty, the same as DefaultQueue.Get
+func (q *PackedQueue) Get() (val interface{}, ok bool, count uint32) {
+	pos := q.pos.Load()
+	read, write := unpackPos(pos)
+
+	cnt := write - read
+	if cnt < 1 {
+		runtime.Gosched()
+		return nil, false, cnt
+	}
+
+	getPosNext := read + 1
+	if !q.pos.CAS(pos, packPos(getPosNext, write)) {
+		runtime.Gosched()
+		return nil, false, cnt
+	}
+
+	val = q.ring.getAt(getPosNext)
+	if val == nil {
+		return nil, false, cnt - 1
+	}
+	return val, true, cnt - 1
+}
+
+// Count the exact number of items in queue at the moment of one Load
+func (q *PackedQueue) Count() uint32 {
+	read, write := unpackPos(q.pos.Load())
+	return write - read
+}
+
+// Capacity the allocated size of queue, always 2's power
+func (q *PackedQueue) Capacity() uint32 {
+	return q.ring.cap
+}
+
+// packPos 各自 32 位溢出回绕，不会进位到另一半
+func packPos(read, write uint32) uint64 {
+	return uint64(read)<<32 | uint64(write)
+}
+
+func unpackPos(pos uint64) (read, write uint32) {
+	return uint32(pos >> 32), uint32(pos)
+}
@@ -0,0 +1,164 @@
+package queue
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPackPos(t *testing.T) {
+	for _, c := range [][2]uint32{{0, 0}, {1, 2}, {0xffffffff, 0}, {0, 0xffffffff}, {0xffffffff, 0xffffffff}, {0x12345678, 0x9abcdef0}} {
+		read, write := unpackPos(packPos(c[0], c[1]))
+		if read != c[0] || write != c[1] {
+			t.Fatalf("unpack(pack(%#x, %#x)) = %#x, %#x", c[0], c[1], read, write)
+		}
+	}
+	// write 回绕不会进位到 read
+	max := uint32(0xffffffff)
+	read, write := unpackPos(packPos(5, max+1))
+	if read != 5 || write != 0 {
+		t.Fatalf("write wrap changed read: %d %d", read, write)
+	}
+}
+
+func TestPackedQueueWrap(t *testing.T) {
+	for _, base := range []uint32{0, 0xfffffff8, 0xffffffff} {
+		q := NewPackedQueue(16)
+		advanceTo(q.ring, base)
+		q.pos.Store(packPos(base, base))
+
+		for round := 0; round < 3; round++ {
+			for i := 0; i < 14; i++ {
+				if ok, cnt := q.Put(i); !ok || cnt != uint32(i+1) {
+					t.Fatalf("base %#x: put %d ok %v count %d", base, i, ok, cnt)
+				}
+			}
+			if ok, _ := q.Put(14); ok {
+				t.Fatalf("base %#x: put into full queue", base)
+			}
+			if q.Count() != 14 {
+				t.Fatalf("base %#x: count %d, want 14", base, q.Count())
+			}
+			for i := 0; i < 14; i++ {
+				if val, ok, cnt := q.Get(); !ok || val != i || cnt != uint32(13-i) {
+					t.Fatalf("base %#x: got %v %v %d, want %d", base, val, ok, cnt, i)
+				}
+			}
+			if _, ok, cnt := q.Get(); ok || cnt != 0 {
+				t.Fatalf("base %#x: get from empty queue", base)
+			}
+		}
+	}
+}
+
+func TestPackedQueueNoLoss(t *testing.T) {
+	produceConsume(t, NewPackedQueue(64), 4, 1, 2000)
+}
+
+// benchPair 4 个生产者和 4 个消费者一共传递 b.N 个数据
+func benchPair(b *testing.B, put func(interface{}) bool, get func() bool) {
+	var wg sync.WaitGroup
+	var left int64 = int64(b.N)
+	per := b.N / 4
+	for p := 0; p < 4; p++ {
+		n := per
+		if p == 0 {
+			n += b.N - 4*per
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for i := 0; i < n; {
+				if put(i) {
+					i++
+				}
+			}
+		}(n)
+	}
+	for c := 0; c < 4; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt64(&left) > 0 {
+				if get() {
+					atomic.AddInt64(&left, -1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkPackedThroughput(b *testing.B) {
+	q := NewPackedQueue(1024)
+	benchPair(b,
+		func(v interface{}) bool { ok, _ := q.Put(v); return ok },
+		func() bool { _, ok, _ := q.Get(); return ok })
+}
+
+func BenchmarkSplitThroughput(b *testing.B) {
+	q := newDefaultQueue(1024)
+	benchPair(b,
+		func(v interface{}) bool { ok, _ := q.Put(v); return ok },
+		func() bool { _, ok, _ := q.Get(); return ok })
+}
+
+// benchCountAccuracy 读写的同时采样 b.N 次数量，报告超出 [0, 可用容量] 的比例
+func benchCountAccuracy(b *testing.B, usable uint32, q Queue, raw func() int32) {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					q.Put(1)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					q.Get()
+				}
+			}
+		}()
+	}
+	bad := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if n := raw(); n < 0 || uint32(n) > usable {
+			bad++
+		}
+	}
+	b.StopTimer()
+	close(stop)
+	wg.Wait()
+	b.ReportMetric(float64(bad)/float64(b.N), "invalid/op")
+}
+
+func BenchmarkPackedCountAccuracy(b *testing.B) {
+	q := NewPackedQueue(64)
+	benchCountAccuracy(b, 62, q, func() int32 {
+		read, write := unpackPos(q.pos.Load())
+		return int32(write - read)
+	})
+}
+
+func BenchmarkSplitCountAccuracy(b *testing.B) {
+	q := newDefaultQueue(64)
+	benchCountAccuracy(b, 62, q, func() int32 {
+		// 同 Count 的两次 Load，不限制范围
+		read := q.read.Load()
+		write := q.write.Load()
+		return int32(write - read)
+	})
+}
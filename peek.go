@@ -0,0 +1,137 @@
+package queue
+
+/*
+ @File : peek.go
+ @Description: read the items without taking them out, best effort under concurrent Put/Get
+ @Time : 2026/10/15
+*/
+
+import "runtime"
+
+// Peek return the head item without taking it, ok false if empty or the head is not written yet.
+// Best effort, the item may be taken by a consumer right after
+func (q *DefaultQueue) Peek() (val interface{}, ok bool) {
+	read := q.read.Load()
+	write := q.write.Load()
+	if q.posCount(read, write) < 1 {
+		return nil, false
+	}
+	val, ok = q.peekCommitted(read + 1)
+	return val, ok && val != nil
+}
+
+// PeekAt return the item offset positions from the head without taking it, 0 is the head, the same as Peek.
+// ok false if offset is out of the count, or the item is not written yet.
+// Best effort, the head may move between reading the positions and the item, so it may be the item behind
+func (q *DefaultQueue) PeekAt(offset int) (val interface{}, ok bool) {
+	read := q.read.Load()
+	write := q.write.Load()
+	if offset < 0 || uint32(offset) >= q.posCount(read, write) {
+		return nil, false
+	}
+	val, ok = q.peekCommitted(read + 1 + uint32(offset))
+	return val, ok && val != nil
+}
+
+// Snapshot copy the items in queue from head to tail without taking them,
+// best effort, see Range
+func (q *DefaultQueue) Snapshot() []interface{} {
+	vals := make([]interface{}, 0, q.Count())
+	q.Range(func(val interface{}) bool {
+		vals = append(vals, val)
+		return true
+	})
+	return vals
+}
+
+// snapshotRetries ConsistentSnapshot 最多尝试的次数
+const snapshotRetries = 8
+
+// ConsistentSnapshot copy the items in queue from head to tail without taking them,
+// unlike Snapshot the result is a coherent view: every item between head and tail is committed,
+// and read/write are not changed during the walk.
+// Return ok false if it can't get a stable view after several tries, under heavy Put/Get
+func (q *DefaultQueue) ConsistentSnapshot() (vals []interface{}, ok bool) {
+	for i := uint32(0); i < snapshotRetries; i++ {
+		if vals, ok = q.snapshotOnce(); ok {
+			return vals, true
+		}
+		backoff(i)
+	}
+	return nil, false
+}
+
+// snapshotOnce 尝试一次一致快照，有位置没写完或者遍历过程中读写位置变化都返回 false
+func (q *DefaultQueue) snapshotOnce() ([]interface{}, bool) {
+	read := q.read.Load()
+	write := q.write.Load()
+	cnt := q.posCount(read, write)
+	vals := make([]interface{}, 0, cnt)
+	for pos := read + 1; pos != read+1+cnt; pos++ {
+		val, ok := q.loadValue(pos)
+		if !ok {
+			return nil, false
+		}
+		if val == tombstone {
+			continue
+		}
+		vals = append(vals, val)
+	}
+	// 读写位置都没有变化，说明遍历期间没有 Put/Get，读到的就是同一时刻的队列
+	if q.read.Load() != read || q.write.Load() != write {
+		return nil, false
+	}
+	return vals, true
+}
+
+// Range call fn for each item from head to tail without taking them, stop when fn return false.
+// Best effort, the positions are read once at the beginning,
+// the items taken by consumers during Range are skipped, and the items put after are not visited
+func (q *DefaultQueue) Range(fn func(val interface{}) bool) {
+	read := q.read.Load()
+	write := q.write.Load()
+	for pos := read + 1; pos != write+1; pos++ {
+		val, ok := q.peekCommitted(pos)
+		if !ok || val == nil {
+			continue
+		}
+		if !fn(val) {
+			return
+		}
+	}
+}
+
+// peekCommitted 读取 pos 位置已经写完的数据，不等待，没写完或者已经被取走返回 false
+func (q *DefaultQueue) peekCommitted(pos uint32) (val interface{}, ok bool) {
+	val, ok = q.loadValue(pos)
+	if !ok {
+		return nil, false
+	}
+	if val == tombstone {
+		return nil, true
+	}
+	return val, true
+}
+
+// loadValue 不占位置读 pos 的槽中已经写完的值，没写完或者已经被取走返回 false。
+// 先把槽的 readID 从 pos 改成 pos-1 钉住它，这个值不属于该槽的任何一轮：
+// 消费者要等 readID 变回 pos 才清空，下一轮的生产者要等 readID 变成 pos+cap 才写入，
+// 读值期间没有人会改它，读完再改回 pos。钉住和改回都是原子操作，读值和写值之间有 happens-before
+func (q *DefaultQueue) loadValue(pos uint32) (val interface{}, ok bool) {
+	cache := &q.carrier[pos&q.capMod]
+	for {
+		readID := cache.readID.Load()
+		writeID := cache.writeID.Load()
+		if writeID != pos+q.cap || (readID != pos && readID != pos-1) {
+			return nil, false
+		}
+		if readID == pos && cache.readID.CAS(pos, pos-1) {
+			break
+		}
+		// 别的 Peek 正在读，很快会改回来
+		runtime.Gosched()
+	}
+	val = cache.value
+	cache.readID.Store(pos)
+	return val, true
+}
@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConsistentSnapshotQuiet(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(10))
+	vals, ok := q.ConsistentSnapshot()
+	if !ok {
+		t.Fatal("no stable snapshot without Put/Get")
+	}
+	assertSeq(t, vals, 0, 10)
+	if q.Count() != 10 {
+		t.Fatalf("snapshot took items, count %d", q.Count())
+	}
+}
+
+func TestConsistentSnapshotUncommitted(t *testing.T) {
+	q := newDefaultQueue(16)
+	q.PutSlice(ints(3))
+	token, _ := q.Reserve()
+	// 有位置没写完，不是一致的视图
+	if _, ok := q.ConsistentSnapshot(); ok {
+		t.Fatal("snapshot ok with an uncommitted position")
+	}
+	token.Commit(3)
+	vals, ok := q.ConsistentSnapshot()
+	if !ok {
+		t.Fatal("no snapshot after commit")
+	}
+	assertSeq(t, vals, 0, 4)
+}
+
+func TestConsistentSnapshotStress(t *testing.T) {
+	q := newDefaultQueue(64)
+	const total = 200000
+	var stop int32
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < total; {
+			if ok, _ := q.Put(i); ok {
+				i++
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer atomic.StoreInt32(&stop, 1)
+		for i := 0; i < total; {
+			if _, ok, _ := q.Get(); ok {
+				i++
+			}
+		}
+	}()
+
+	// 单生产者单消费者时队列里总是连续的一段，一致的快照也必须是连续的一段
+	stable := 0
+	for atomic.LoadInt32(&stop) == 0 {
+		runtime.Gosched()
+		vals, ok := q.ConsistentSnapshot()
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		stable++
+		for i := 1; i < len(vals); i++ {
+			if vals[i].(int) != vals[i-1].(int)+1 {
+				t.Fatalf("incoherent snapshot %v", vals)
+			}
+		}
+	}
+	wg.Wait()
+	t.Logf("%d stable non-empty snapshots", stable)
+}
+
+func TestPeekAt(t *testing.T) {
+	q := newDefaultQueue(8)
+	if val, ok := q.PeekAt(0); ok || val != nil {
+		t.Fatalf("peek %v %v on empty queue", val, ok)
+	}
+	// 跨过环的末尾
+	advanceTo(q, 6)
+	q.PutSlice([]interface{}{"a", "b", "c"})
+	for offset, want := range []string{"a", "b", "c"} {
+		if val, ok := q.PeekAt(offset); !ok || val != want {
+			t.Fatalf("offset %d: peek %v %v, want %s", offset, val, ok, want)
+		}
+	}
+	// 超出数量，以及负数
+	for _, offset := range []int{3, 100, -1} {
+		if val, ok := q.PeekAt(offset); ok || val != nil {
+			t.Fatalf("offset %d: peek %v %v beyond the count", offset, val, ok)
+		}
+	}
+	// 不取走
+	if q.Count() != 3 {
+		t.Fatalf("count %d after peeking", q.Count())
+	}
+	q.Get()
+	if val, ok := q.PeekAt(1); !ok || val != "c" {
+		t.Fatalf("offset 1 after get: peek %v %v, want c", val, ok)
+	}
+}
+
+func TestPeekAtUncommitted(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.Put(1)
+	token, _ := q.Reserve()
+	q.Put(3)
+	// 占了位置还没写完的不返回，后面的不受影响
+	if val, ok := q.PeekAt(1); ok {
+		t.Fatalf("peek %v at a reserved slot", val)
+	}
+	if val, ok := q.PeekAt(2); !ok || val != 3 {
+		t.Fatalf("peek %v %v behind a reserved slot, want 3", val, ok)
+	}
+	token.Commit(2)
+	if val, ok := q.PeekAt(1); !ok || val != 2 {
+		t.Fatalf("peek %v %v after commit, want 2", val, ok)
+	}
+}
+
+func TestPeekDuringGet(t *testing.T) {
+	// race detector 下也要干净，Peek 读到的只能是还在队列里的值，不能是被清空的 nil
+	q := newDefaultQueue(16, WithStrictChecks())
+	const total = 20000
+	var stop int32
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= total; {
+			if ok, _ := q.Put(i); ok {
+				i++
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer atomic.StoreInt32(&stop, 1)
+		for i := 1; i <= total; {
+			val, ok, _ := q.Get()
+			if !ok {
+				continue
+			}
+			if val != i {
+				t.Errorf("get %v, want %d", val, i)
+				return
+			}
+			i++
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for atomic.LoadInt32(&stop) == 0 {
+			if val, ok := q.Peek(); ok && val == nil {
+				t.Error("peek a cleared slot")
+				return
+			}
+			for _, val := range q.Snapshot() {
+				if val == nil {
+					t.Error("snapshot a cleared slot")
+					return
+				}
+			}
+			runtime.Gosched()
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,31 @@
+package queue
+
+/*
+ @File : pipeline.go
+ @Description: wire a producer, a queue and a group of consumers into a complete pipeline
+ @Time : 2026/10/15
+*/
+
+import "context"
+
+// Pipeline create a queue with cap, put all the items from gen into it,
+// and run workers consumers passing each item to sink exactly once.
+// Return after gen is exhausted and all items are consumed, or ctx is done,
+// all goroutines are stopped when it returns, the error is from Produce, nil if finished
+func Pipeline(ctx context.Context, gen func() (val interface{}, ok bool), cap uint32, workers int,
+	sink func(val interface{})) error {
+	q := newDefaultQueue(cap)
+	if workers < 1 {
+		workers = 1 // 没有消费者的话，生产者会一直等待
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		err := q.Produce(ctx, gen)
+		q.Close() // 生产完关闭，消费者取完剩下的数据后退出
+		errc <- err
+	}()
+
+	q.ConsumerGroup(ctx, workers, sink)
+	return <-errc
+}
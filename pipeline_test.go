@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPipelineExactlyOnce(t *testing.T) {
+	const total = 20000
+	next := 0
+	gen := func() (interface{}, bool) {
+		if next == total {
+			return nil, false
+		}
+		next++
+		return next - 1, true
+	}
+
+	var mu sync.Mutex
+	seen := make([]int, total)
+	err := Pipeline(context.Background(), gen, 16, 4, func(val interface{}) {
+		mu.Lock()
+		seen[val.(int)]++
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("pipeline error %v", err)
+	}
+	// Pipeline 返回时所有数据都已经交给 sink
+	for i, n := range seen {
+		if n != 1 {
+			t.Fatalf("item %d reached sink %d times", i, n)
+		}
+	}
+}
+
+func TestPipelineCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	gen := func() (interface{}, bool) { return 1, true } // 永远不会结束
+	var mu sync.Mutex
+	got := 0
+	done := make(chan error)
+	go func() {
+		done <- Pipeline(ctx, gen, 16, 2, func(interface{}) {
+			mu.Lock()
+			got++
+			mu.Unlock()
+			time.Sleep(time.Microsecond)
+		})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("pipeline error %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline didn't stop after cancel")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got == 0 {
+		t.Fatal("sink never called before cancel")
+	}
+}
@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+)
+
+type pooledValue struct {
+	A, B int
+}
+
+func TestValuePoolReuse(t *testing.T) {
+	news := 0
+	pool := &sync.Pool{New: func() interface{} {
+		news++
+		return new(pooledValue)
+	}}
+	q := newDefaultQueue(8, WithValuePool(pool))
+
+	const cycles = 100
+	for i := 0; i < cycles; i++ {
+		v := q.AcquireValue().(*pooledValue)
+		v.A, v.B = i, -i
+		if ok, _ := q.Put(v); !ok {
+			t.Fatal("put failed")
+		}
+		val, ok, _ := q.Get()
+		if !ok {
+			t.Fatal("get failed")
+		}
+		got := val.(*pooledValue)
+		if got.A != i || got.B != -i {
+			t.Fatalf("cycle %d: got %+v", i, *got)
+		}
+		q.ReleaseValue(got)
+	}
+	// race 模式下 sync.Pool 会随机丢弃，只要求大部分被复用
+	if news > cycles/2 {
+		t.Fatalf("%d wrappers allocated for %d cycles, not reused", news, cycles)
+	}
+}
+
+func TestValuePoolNone(t *testing.T) {
+	q := newDefaultQueue(8)
+	if v := q.AcquireValue(); v != nil {
+		t.Fatalf("AcquireValue without pool return %v", v)
+	}
+	q.ReleaseValue(1) // 没有 pool 时什么都不做
+}
@@ -0,0 +1,62 @@
+package queue
+
+/*
+ @File : priority.go
+ @Description: priority by tiers, each tier is a DefaultQueue, Get prefer the higher tier strictly
+ @Time : 2026/10/15
+*/
+
+// PriorityQueue hold tiers DefaultQueue, tier 0 is the highest priority.
+// It's priority by tier, not a heap with comparator, so it keeps lock free,
+// items in the same tier are FIFO
+type PriorityQueue struct {
+	tiers []*DefaultQueue
+}
+
+// NewPriorityQueue alloc a PriorityQueue with tiers levels, each level has cap
+func NewPriorityQueue(cap uint32, tiers int) *PriorityQueue {
+	if tiers < 1 {
+		tiers = 1
+	}
+	q := &PriorityQueue{tiers: make([]*DefaultQueue, tiers)}
+	for i := range q.tiers {
+		q.tiers[i] = newDefaultQueue(cap)
+	}
+	return q
+}
+
+// Put put val into the tier, May failed if lock slot failed or the tier is full or tier out of range,
+// count is the number of items in the tier
+func (q *PriorityQueue) Put(val interface{}, tier int) (ok bool, count uint32) {
+	if tier < 0 || tier >= len(q.tiers) {
+		return false, 0
+	}
+	return q.tiers[tier].Put(val)
+}
+
+// Get get from the highest tier which is not empty, count is the total number of items in all tiers
+func (q *PriorityQueue) Get() (val interface{}, ok bool, count uint32) {
+	for _, t := range q.tiers {
+		// 高优先级还有数据时只是占位失败，继续重试，不能去取低优先级的
+		for t.Count() > 0 {
+			if val, ok, _ = t.Get(); ok {
+				return val, true, q.Count()
+			}
+		}
+	}
+	return nil, false, q.Count()
+}
+
+// Count the total number of items in all tiers
+func (q *PriorityQueue) Count() uint32 {
+	var n uint32
+	for _, t := range q.tiers {
+		n += t.Count()
+	}
+	return n
+}
+
+// Tiers the number of priority levels
+func (q *PriorityQueue) Tiers() int {
+	return len(q.tiers)
+}
@@ -0,0 +1,49 @@
+package queue
+
+import "testing"
+
+func TestPriorityQueueOrder(t *testing.T) {
+	q := NewPriorityQueue(16, 3)
+	// 低优先级先放
+	for tier := 2; tier >= 0; tier-- {
+		for i := 0; i < 3; i++ {
+			if ok, _ := q.Put(tier*10+i, tier); !ok {
+				t.Fatalf("put into tier %d failed", tier)
+			}
+		}
+	}
+	if q.Count() != 9 {
+		t.Fatalf("count %d, want 9", q.Count())
+	}
+
+	want := []int{0, 1, 2, 10, 11, 12, 20, 21, 22}
+	for i, w := range want {
+		// 中途放入的高优先级数据排在剩下的低优先级前面
+		if i == 4 {
+			q.Put(-1, 0)
+			if val, _, _ := q.Get(); val != -1 {
+				t.Fatalf("got %v, want the new tier 0 item", val)
+			}
+		}
+		val, ok, cnt := q.Get()
+		if !ok || val != w || cnt != uint32(len(want)-1-i) {
+			t.Fatalf("got %v %v %d, want %d", val, ok, cnt, w)
+		}
+	}
+	if _, ok, _ := q.Get(); ok {
+		t.Fatal("get from empty queue")
+	}
+}
+
+func TestPriorityQueueTierRange(t *testing.T) {
+	q := NewPriorityQueue(8, 0)
+	if q.Tiers() != 1 {
+		t.Fatalf("tiers %d, want at least 1", q.Tiers())
+	}
+	if ok, _ := q.Put(1, 1); ok {
+		t.Fatal("put into tier out of range")
+	}
+	if ok, _ := q.Put(1, -1); ok {
+		t.Fatal("put into negative tier")
+	}
+}
@@ -9,12 +9,20 @@ package queue
 */
 
 import (
+	"context"
+	"fmt"
 	"runtime"
+	"sync"
+	"time"
+	"unsafe"
 
 	"go.uber.org/atomic"
 )
 
-var MinCap uint32 = 8 // 最小队列长度，防止队列过小，竞争太激烈； 理论上越大冲突越小
+var MinCap uint32 = 8          // 最小队列长度，防止队列过小，竞争太激烈； 理论上越大冲突越小
+var FullStreak uint32 = 64     // 连续多少次 Put 遇到队列满，才触发一次 WithFullCallback 的回调
+var EmptyStreak uint32 = 64    // 连续多少次 Get 遇到队列空，才触发一次 WithEmptyCallback 的回调
+var SlotsPerWorker uint32 = 32 // SuggestCapacity 给每个生产者或消费者预留的槽数
 // MaxWait = 100 // 当出现饥饿竞态时，最多让出cpu的次数
 
 type Queue interface {
@@ -31,6 +39,24 @@ type Queue interface {
 	// Puts(values []interface{}) (puts, count uint32)
 }
 
+// InspectableQueue Queue which can report its state,
+// a separate interface so that the implementers of Queue don't need to change
+type InspectableQueue interface {
+	Queue
+	Info() string
+	Capacity() uint32
+	Count() uint32
+}
+
+// AsInspectable return q as InspectableQueue if it implements
+func AsInspectable(q Queue) (InspectableQueue, bool) {
+	iq, ok := q.(InspectableQueue)
+	return iq, ok
+}
+
+// Slot the element of carrier, only for allocating the carrier by the caller, see NewQueueWithCarrier
+type Slot = slot
+
 // 队列的槽，每个槽有一个 writeID 和 readID
 // 当输入新值时，putID将增加cap，这意味着它有值
 // 只有 readID + cap == writeID ，才能从此插槽中获取值，然后 readID 增加 cap，cap 是队列容量，加上 cap 是为了对应下次读写的位置再到该位置
@@ -38,9 +64,73 @@ type Queue interface {
 type slot struct {
 	writeID *atomic.Uint32 // write + n 倍的容量
 	readID  *atomic.Uint32 // write + n 倍的容量
-	value   interface{}
+	entry
+}
+
+// cacheLine cache line 的大小，常见的 CPU 为 64 字节
+const cacheLine = 64
+
+// paddedUint32 独占一个 cache line 的 atomic.Uint32，
+// atomic.Uint32 里有一个按 8 字节对齐的空字段，大小是 8 而不是 4，按实际大小填充
+type paddedUint32 struct {
+	atomic.Uint32
+	_ [cacheLine - unsafe.Sizeof(atomic.Uint32{})]byte
+}
+
+// entry 槽中保存的数据，以及和数据一起写入的附带信息
+type entry struct {
+	value interface{}
+	x     *extra // 附带信息，普通的 Put 为 nil，槽只多一个指针
+}
+
+// extra 少数变体才用到的附带信息，单独分配，不占用每个槽的空间
+type extra struct {
+	done  func()          // 数据被取走后的回调，见 PutCallback
+	stamp int64           // 写入时的时间戳，见 WithTimestamp
+	seq   uint64          // 写入的序号，见 PutSeq
+	ctx   context.Context // 生产者的 context，只用来传递其中的值，见 PutCtx
+}
+
+// more 返回附带信息，没有时分配
+func (e *entry) more() *extra {
+	if e.x == nil {
+		e.x = new(extra)
+	}
+	return e.x
+}
+
+// done 取走后的回调，没有为 nil
+func (e *entry) done() func() {
+	if e.x == nil {
+		return nil
+	}
+	return e.x.done
+}
+
+// stamp 写入时间，没有记录为 0
+func (e *entry) stamp() int64 {
+	if e.x == nil {
+		return 0
+	}
+	return e.x.stamp
 }
 
+// seq PutSeq 的序号，没有为 0
+func (e *entry) seq() uint64 {
+	if e.x == nil {
+		return 0
+	}
+	return e.x.seq
+}
+
+// plain 只有数据没有附带信息，只有这种才能写到磁盘，时间戳在写入时才记录不算
+func (e *entry) plain() bool {
+	return e.x == nil || (e.x.done == nil && e.x.seq == 0 && e.x.ctx == nil)
+}
+
+// tombstone 占了位置但是写入失败的槽写入这个值，消费者取到后当作 nil 跳过，见 SafePut
+var tombstone = &struct{ name string }{"tombstone"}
+
 // DefaultQueue An bounded lock free Queue
 type DefaultQueue struct {
 	cap     uint32         // const after init, always 2's power，初始化后的常量，始终为2的幂
@@ -48,55 +138,386 @@ type DefaultQueue struct {
 	write   *atomic.Uint32 // 写入位置
 	read    *atomic.Uint32 // 读取位置
 	carrier []slot         // 环形数据队列基础数据模型
+
+	closed *atomic.Bool // 关闭后不再接受 Put，剩余的数据仍然可以 Get
+	name   string       // 注册到 registry 中的名称，为空表示不注册
+	id     string       // 日志和监控中区分队列的标识，见 WithID
+	self   Queue        // 注册到 registry 中的对象，Close 时用来注销
+	pool   *sync.Pool   // 值对象的复用池，可以为空
+
+	singleProducer bool // 只有一个生产者，见 SPMCQueue
+	singleConsumer bool // 只有一个消费者，见 MPSCQueue
+
+	latencyHook    func(op string, d time.Duration) // Put/Get 耗时的采样回调
+	latencyEvery   uint32                           // 每多少次操作采样一次
+	latencyCounter *atomic.Uint32                   // 操作次数，用于采样
+
+	spill *spillStore // 队列满后溢出到磁盘的数据，见 WithSpillover
+	stamp bool        // 是否记录每个数据的写入时间
+
+	stats       bool          // 是否统计运行数据，见 WithStats
+	rate        bool          // 是否统计消费的速度，见 WithRateTracking
+	lastGet     *atomic.Int64 // 上一次取走数据的时间
+	getInterval *atomic.Int64 // 取走数据的平均间隔，EWMA
+	metrics     metrics       // 累计的计数，见 SnapshotAndResetStats
+	statsMu     sync.RWMutex  // 累加计数持读锁，Stats 和 SnapshotAndResetStats 持写锁，所有计数在同一时刻读出
+
+	copyFn   func(interface{}) interface{} // 取出数据后先复制一份再返回，见 WithCopyOnGet
+	seqGen   *atomic.Uint64                // PutSeq 的序号
+	padded   bool                          // 槽的 writeID/readID 是否按 cache line 对齐，见 WithPaddedSlots
+	idLines  int                           // 槽的 writeID/readID 各占几个 cache line，见 WithMinLinesPerSlot
+	prefault bool                          // 创建时就把 carrier 的内存都写一遍，见 WithCarrierHint
+
+	fullCallback func(count uint32) // 队列持续满时的回调
+	fullStreak   *atomic.Uint32     // 连续遇到队列满的 Put 次数，Put 成功后清零
+
+	spaceCallback func()       // 队列由满变为不满时的回调，见 WithSpaceAvailable
+	wasFull       *atomic.Bool // 队列满过且还没有触发 spaceCallback
+
+	emptyCallback func()         // 队列持续空时的回调
+	emptyStreak   *atomic.Uint32 // 连续遇到队列空的 Get 次数，Get 成功后清零
+
+	dropAfter uint32 // Get 等待写入超过多少次后放弃该位置，0 表示一直等，见 WithDropAfter
+	strict    bool   // 容量必须是2的幂次，不自动向上取整，见 WithStrictCapacity
+	noYield   bool   // 满、空或者占位失败直接返回，不让出 cpu，见 WithNoYieldOnFail
+
+	reserved *atomic.Uint32 // ReserveCapacity 预留的空间，普通的 Put 不能使用
+
+	tracking bool            // 占位时记录调用方，见 WithCallerTracking
+	callers  []atomic.String // 每个槽最近一次占位的调用方
+
+	fb *fallback // 竞争激烈时退化为加锁，见 WithAutoFallback
+
+	zeroOnReserve bool // 写入前先清空槽，见 WithZeroOnReserve
+
+	checks bool           // 检查使用方式是否违反约定，违反时 panic，见 WithStrictChecks
+	resets *atomic.Uint32 // Reset 的次数，用来发现和 Put/Get 并发的 Reset
+
+	uncommitted UncommittedPolicy // 队头占了位置还没写完时 Get 的做法，见 WithUncommittedPolicy
 }
 
 // NewQueue alloc a fixed size of cap Queue
 // and do some essential init
-func NewQueue(cap uint32) Queue {
+func NewQueue(cap uint32, opts ...Option) Queue {
+	q := newDefaultQueue(cap, opts...)
+	q.registerAs(q)
+	return q
+}
+
+// NewQueueE the same as NewQueue, but return ErrInvalidCapacity if cap is not acceptable,
+// with WithStrictCapacity cap must be 2's power and not less than MinCap,
+// otherwise cap is rounded up as NewQueue does and never failed
+func NewQueueE(cap uint32, opts ...Option) (Queue, error) {
+	// 先在一个临时对象上应用 option，只为了知道是否严格检查，option 只设置字段，没有副作用
+	probe := new(DefaultQueue)
+	for _, opt := range opts {
+		opt(probe)
+	}
+	if probe.strict && (cap < MinCap || cap > 1<<31 || cap&(cap-1) != 0) {
+		return nil, ErrInvalidCapacity
+	}
+	return NewQueue(cap, opts...), nil
+}
 
+// SuggestCapacity a reasonable 2's power cap for NewQueue by the number of concurrent producers and consumers,
+// each goroutine get about SlotsPerWorker slots, bigger cap means less contention on the same slot,
+// and never less than MinCap
+func SuggestCapacity(producers, consumers int) uint32 {
+	workers := producers + consumers
+	if workers < 1 {
+		workers = 1
+	}
+	want := uint64(workers) * uint64(SlotsPerWorker)
+
+	c := uint64(1)
+	for (c < want || c < uint64(MinCap)) && c < 1<<31 {
+		c <<= 1
+	}
+	return uint32(c)
+}
+
+// NewQueueWithCarrier alloc a Queue on the carrier provided by the caller,
+// so the advanced users can back it with their own memory (arena for example),
+// len(carrier) is the cap, must be 2's power and not less than MinCap, otherwise ErrInvalidCapacity.
+// The carrier is owned by the queue after that, the caller must not touch it
+func NewQueueWithCarrier(carrier []Slot, opts ...Option) (Queue, error) {
+	n := len(carrier)
+	if n < int(MinCap) || n > 1<<31 || n&(n-1) != 0 {
+		return nil, ErrInvalidCapacity
+	}
+	q := newQueueOn(uint32(n), carrier, opts...)
+	q.registerAs(q)
+	return q, nil
+}
+
+// newDefaultQueue 初始化队列，不注册，给 NewQueue 以及各种变体队列使用
+func newDefaultQueue(cap uint32, opts ...Option) *DefaultQueue {
+	return newQueueOn(minRoundNumBy2(cap), nil, opts...)
+}
+
+// newQueueOn 在 carrier 上初始化队列，carrier 为空时分配新的，不为空时长度必须等于 cap
+func newQueueOn(cap uint32, carrier []slot, opts ...Option) *DefaultQueue {
 	q := new(DefaultQueue)
-	q.cap = q.minRoundNumBy2(cap)
+	q.cap = cap
 	q.capMod = q.cap - 1
 	q.write = atomic.NewUint32(0)
 	q.read = atomic.NewUint32(0)
-	q.carrier = make([]slot, q.cap)
+	q.closed = atomic.NewBool(false)
+	q.fullStreak = atomic.NewUint32(0)
+	q.wasFull = atomic.NewBool(false)
+	q.emptyStreak = atomic.NewUint32(0)
+	q.reserved = atomic.NewUint32(0)
+	q.latencyEvery = 1
+	q.latencyCounter = atomic.NewUint32(0)
+	q.lastGet = atomic.NewInt64(0)
+	q.getInterval = atomic.NewInt64(0)
+	q.metrics = newMetrics()
+	q.resets = atomic.NewUint32(0)
+	q.seqGen = atomic.NewUint64(0)
 
-	// writeID/readID 提前分配好，每用一个，delta增加一轮
-	tmp := &q.carrier[0]
-	tmp.writeID = atomic.NewUint32(q.cap)
-	tmp.readID = atomic.NewUint32(q.cap)
+	// 槽的分配方式可能被 option 修改，先应用 option
+	for _, opt := range opts {
+		opt(q)
+	}
 
-	var i uint32 = 1
+	q.carrier = carrier
+	if q.carrier == nil {
+		q.carrier = make([]slot, q.cap)
+	}
+	// writeID/readID 提前分配好，每用一个，delta增加一轮
+	var i uint32
 	for ; i < q.cap; i++ {
-		tmp = &q.carrier[i]
-		tmp.readID = atomic.NewUint32(i)
-		tmp.writeID = atomic.NewUint32(i)
+		tmp := &q.carrier[i]
+		tmp.entry = entry{}
+		tmp.readID = q.newID(q.initID(i))
+		tmp.writeID = q.newID(q.initID(i))
+	}
+	if q.tracking {
+		q.callers = make([]atomic.String, q.cap)
+	}
+	if q.prefault {
+		q.Warm()
 	}
-
 	return q
 }
 
+// newID 分配槽的 writeID/readID，WithPaddedSlots 时每个独占一个 cache line，
+// WithMinLinesPerSlot 时连续分配多个 cache line 只用第一个，后面的都是填充
+func (q *DefaultQueue) newID(val uint32) *atomic.Uint32 {
+	if q.idLines > 1 {
+		p := make([]paddedUint32, q.idLines)
+		p[0].Store(val)
+		return &p[0].Uint32
+	}
+	if q.padded || q.idLines == 1 {
+		p := new(paddedUint32)
+		p.Store(val)
+		return &p.Uint32
+	}
+	return atomic.NewUint32(val)
+}
+
+// registerAs 如果设置了 WithRegistry，把 self 注册进 registry，
+// 变体队列（比如 MPSCQueue）注册的是外层的对象而不是内部的 DefaultQueue
+func (q *DefaultQueue) registerAs(self Queue) {
+	if q.name == "" {
+		return
+	}
+	q.self = self
+	register(q.name, self)
+}
+
 // Put May failed if lock slot failed or full
 // caller should retry if failed
-// should not put nil for normal logic
+// should not put nil for normal logic.
+// Everything written to val before Put is visible to the consumer which gets it,
+// so a pointer to a freshly built struct can be put without extra synchronization
 func (q *DefaultQueue) Put(val interface{}) (ok bool, count uint32) {
+	if q.latencyHook != nil && q.sampleLatency() {
+		start := time.Now()
+		ok, count = q.put(entry{value: val})
+		q.latencyHook("put", time.Since(start))
+		return ok, count
+	}
+	return q.put(entry{value: val})
+}
+
+// put 写入数据以及附带信息
+func (q *DefaultQueue) put(e entry) (ok bool, count uint32) {
+	_, ok, count = q.putPos(e)
+	return ok, count
+}
+
+// putPos 同 put，同时返回占到的位置，没有占到位置或者写到了磁盘时 pos 为 0
+func (q *DefaultQueue) putPos(e entry) (pos uint32, ok bool, count uint32) {
+	pos, count, err := q.putPosErr(e)
+	return pos, err == nil, count
+}
+
+// putPosErr 同 putPos，失败时返回原因：ErrClosed、ErrFull、errConflict（占位失败，可以马上重试）或者写磁盘的错误
+func (q *DefaultQueue) putPosErr(e entry) (pos uint32, count uint32, err error) {
+	if q.checks {
+		defer q.checkEpoch("Put", q.resets.Load())
+	}
+	if q.fb != nil && q.fb.on.Load() {
+		q.fb.lock()
+		defer q.fb.unlock()
+	}
 	read := q.read.Load()
 	write := q.write.Load()
 
 	cnt := q.posCount(read, write)
-	// 如果满了，就直接失败
-	if cnt >= q.capMod-1 {
-		runtime.Gosched() // 当有其他待执行的逻辑时，比如有很多其他 Put，这里能马上给其他put使用，有空了再来return
-		return false, cnt
+	// 关闭后不再接受新的数据
+	if q.closed.Load() {
+		return 0, cnt, ErrClosed
+	}
+	// 磁盘上还有数据时，为了保证顺序新数据也只能写到磁盘
+	if q.spill != nil && (cnt >= q.usable() || q.spill.len() > 0) {
+		if !e.plain() {
+			return 0, cnt, errNotPlain // 回调等附带信息没法写到磁盘
+		}
+		if err := q.spill.push(e.value); err != nil {
+			return 0, cnt, err
+		}
+		return 0, cnt, nil
+	}
+	// 如果满了，就直接失败，预留的空间也当作满
+	if cnt >= q.usable() {
+		q.onFull(cnt)
+		q.yield() // 当有其他待执行的逻辑时，比如有很多其他 Put，这里能马上给其他put使用，有空了再来return
+		return 0, cnt, ErrFull
 	}
 
 	// 先占一个坑，如果占坑失败，就直接返回
 	posNext := write + 1
-	if !q.write.CAS(write, posNext) {
+	if !q.casWrite(write, posNext) {
+		q.yield()
+		return 0, cnt, errConflict
+	}
+	q.track(posNext, "")
+
+	q.putAt(posNext, e)
+	q.onPut(1)
+	return posNext, cnt + 1, nil
+}
+
+// Get May failed if lock slot failed or empty
+// caller should retry if failed, val nil also means false.
+// Producers never make Get fail, they CAS write while consumers CAS read,
+// so a single consumer keeps making progress however many producers there are.
+// With WithAutoFallback it's different: after the producers' CAS failures switch the queue into mutex mode,
+// Get takes the same mutex and waits behind the producers, it still progresses but may wait for several Put.
+// There is no fairness among consumers
+func (q *DefaultQueue) Get() (val interface{}, ok bool, count uint32) {
+	if q.latencyHook != nil && q.sampleLatency() {
+		start := time.Now()
+		val, ok, count = q.get()
+		q.latencyHook("get", time.Since(start))
+		return val, ok, count
+	}
+	return q.get()
+}
+
+func (q *DefaultQueue) get() (val interface{}, ok bool, count uint32) {
+	e, ok, count := q.getEntry()
+	return e.value, ok, count
+}
+
+// getEntry 取出数据以及附带信息，数据为 nil 时 ok 也为 false
+func (q *DefaultQueue) getEntry() (e entry, ok bool, count uint32) {
+	e, _, ok, count = q.getEntryPos()
+	return e, ok, count
+}
+
+// getEntryPos 同 getEntry，同时返回取的位置，没有占到位置时 pos 为 0
+func (q *DefaultQueue) getEntryPos() (e entry, pos uint32, ok bool, count uint32) {
+	if q.checks {
+		defer q.checkEpoch("Get", q.resets.Load())
+	}
+	if q.fb != nil && q.fb.on.Load() {
+		q.fb.lock()
+		defer q.fb.unlock()
+	}
+	if q.spill != nil && q.spill.len() > 0 {
+		q.refill()
+	}
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt := q.posCount(read, write)
+	if cnt < 1 {
+		q.onEmpty()
+		q.yield()
+		return e, 0, false, cnt
+	}
+
+	getPosNext := read + 1
+	if q.uncommitted != SpinOnUncommitted && !q.committed(getPosNext) {
+		q.yield()
+		if q.uncommitted == EmptyOnUncommitted {
+			return e, 0, false, 0
+		}
+		return e, 0, false, cnt
+	}
+	if !q.casRead(read, getPosNext) {
+		q.yield()
+		return e, 0, false, cnt
+	}
+
+	e = q.takeAt(getPosNext)
+	return e, getPosNext, e.value != nil, cnt - 1
+}
+
+// yield Put/Get 失败返回前让出 cpu，给其他 goroutine 机会推进读写，WithNoYieldOnFail 时不让出
+func (q *DefaultQueue) yield() {
+	if !q.noYield {
 		runtime.Gosched()
-		return false, cnt
 	}
+}
+
+// casWrite 占写的位置，单生产者（SPMCQueue）时只有自己修改 write，直接 Store 不需要 CAS，
+// 但是开启溢出到磁盘后消费者也会在 refill 中占写的位置，仍然要 CAS
+func (q *DefaultQueue) casWrite(old, new uint32) bool {
+	if q.singleProducer && q.spill == nil {
+		if q.checks && !q.write.CAS(old, new) {
+			panic(q.tag() + ": strict check: concurrent producers on a single producer queue")
+		}
+		q.write.Store(new)
+		return true
+	}
+	ok := q.write.CAS(old, new)
+	if !ok && q.stats {
+		q.metrics.putRetries.Inc()
+	}
+	if q.fb != nil {
+		return q.fb.record(ok)
+	}
+	return ok
+}
+
+// casRead 占读的位置，单消费者（MPSCQueue）时只有自己修改 read，直接 Store 不需要 CAS
+func (q *DefaultQueue) casRead(old, new uint32) bool {
+	if q.singleConsumer {
+		if q.checks && !q.read.CAS(old, new) {
+			panic(q.tag() + ": strict check: concurrent consumers on a single consumer queue")
+		}
+		q.read.Store(new)
+		return true
+	}
+	ok := q.read.CAS(old, new)
+	if !ok && q.stats {
+		q.metrics.getRetries.Inc()
+	}
+	if q.fb != nil {
+		return q.fb.record(ok)
+	}
+	return ok
+}
 
+// putAt 向已经占到的 posNext 位置写入数据，直到写入成功才返回
+// 数据必须在 writeID 增加之前写入：writeID 的原子写是发布，消费者原子读到新的 writeID 之后再读数据，
+// 按 go 的内存模型，写入数据 happens-before 消费者读数据，消费者能看到完整构造好的对象，两者顺序不能调换
+func (q *DefaultQueue) putAt(posNext uint32, e entry) {
 	var cache *slot = &q.carrier[posNext&q.capMod] // 位操作与上 capMod 对应取余操作，capMod 比 队列长度少1，所以最高位位0，去掉最高位的操作就是取余
 	// var waitCounter = 0
 	for {
@@ -107,9 +528,28 @@ func (q *DefaultQueue) Put(val interface{}) (ok bool, count uint32) {
 		// readID == writeID 表示还是空的，如果有写入 writeID 就会 add 一个长度就会比 readID 大，由此来标记获取到锁后该槽是否为空
 		// 同时这里为什么放在 for 里面也是这个原因，可能情况是读的操作到了这个槽的位置，但是他还没来得及写进去（已经获取到锁的状态），那就要for 多试几次，读和写同理
 		if posNext == writeID && readID == writeID {
-			cache.value = val
+			if q.zeroOnReserve {
+				// Get 取走时已经清空过，这里再清一次，防止其他路径遗留的旧数据在写入前被读到
+				cache.entry = entry{}
+			}
+			if q.stamp && e.stamp() == 0 {
+				e.more().stamp = time.Now().UnixNano()
+			}
+			if q.dropAfter > 0 {
+				// 消费者可能放弃这个位置，先把 writeID 改成 posNext-1 标记正在写，抢不到说明已经被放弃
+				if !cache.writeID.CAS(writeID, posNext-1) {
+					continue
+				}
+				cache.entry = e
+				cache.writeID.Store(posNext + q.cap)
+				return
+			}
+			cache.entry = e
 			cache.writeID.Add(q.cap) // 为什么不需要锁，当读与写都在一个位置的时候，因为这里加上一个 cap，在 get 的时候判断是否有增加过 cap 来判断是否已经赋值完毕，光获取到当前的 write 标识还不够
-			return true, cnt + 1
+			return
+		} else if q.dropAfter > 0 && int32(writeID-posNext) > 0 {
+			// 这个位置等太久被消费者放弃了（见 takeAt），数据丢弃
+			return
 		} else {
 			// @review 是否要加失败跳出待定
 
@@ -132,76 +572,279 @@ func (q *DefaultQueue) Put(val interface{}) (ok bool, count uint32) {
 			// 本来为了让其他操作不过度等待加的数据丢弃，发现这部分在大量 put （出现内扣一圈，这个位置又被put），不能保证原子性（因为获取锁和写入分离）
 			// 这个 else 里的 q.Get() 不能保证取到放入的数据，可能数据还没放进去
 			// 也就是说，如果在大量写入的情况下，相同位置被下一个循环覆盖写入
+			if q.checks {
+				q.checkSlot("Put", posNext, readID, writeID)
+			}
 			runtime.Gosched()
 		}
 
 	}
 }
 
-// Get May failed if lock slot failed or empty
-// caller should retry if failed, val nil also means false
-func (q *DefaultQueue) Get() (val interface{}, ok bool, count uint32) {
-	read := q.read.Load()
-	write := q.write.Load()
-
-	cnt := q.posCount(read, write)
-	if cnt < 1 {
-		runtime.Gosched()
-		return nil, false, cnt
-	}
-
-	getPosNext := read + 1
-	if !q.read.CAS(read, getPosNext) {
-		runtime.Gosched()
-		return nil, false, cnt
-	}
+// getAt 从已经占到的 getPosNext 位置取出数据并清空该槽，直到该位置写入完成才返回
+func (q *DefaultQueue) getAt(getPosNext uint32) (val interface{}) {
+	return q.takeAt(getPosNext).value
+}
 
+// takeAt 从已经占到的 getPosNext 位置取出数据以及附带信息并清空该槽，直到该位置写入完成才返回，
+// 槽释放之后调用 PutCallback 设置的回调
+func (q *DefaultQueue) takeAt(getPosNext uint32) (e entry) {
 	cache := &q.carrier[getPosNext&q.capMod]
 
 	// var waitCounter = 0
-	for {
+	for spins := uint32(0); ; spins++ {
 		readID := cache.readID.Load()
 		writeID := cache.writeID.Load()
+		if q.dropAfter > 0 && spins >= q.dropAfter && getPosNext == readID && getPosNext == writeID &&
+			cache.writeID.CAS(writeID, writeID+q.cap) {
+			// 生产者占了位置一直没开始写，放弃这个位置，相当于写入了 tombstone，之后生产者发现后丢弃它的数据
+			cache.readID.Add(q.cap)
+			q.onGet()
+			q.onSpace()
+			return entry{}
+		}
 		if getPosNext == readID && (readID+q.cap == writeID) {
-			val = cache.value
-			cache.value = nil
+			e = cache.entry
+			cache.entry = entry{}
 			cache.readID.Add(q.cap)
-			ret := true
-			if val == nil {
-				ret = false
+			q.onGet()
+			q.onSpace()
+			if done := e.done(); done != nil {
+				done()
 			}
-			return val, ret, cnt - 1
+			if e.value == tombstone {
+				e.value = nil
+			}
+			if q.copyFn != nil && e.value != nil {
+				e.value = q.copyFn(e.value)
+			}
+			return e
 		} else {
+			if q.checks {
+				q.checkSlot("Get", getPosNext, readID, writeID)
+			}
 			runtime.Gosched()
 		}
 	}
 }
 
+// peekAt 读取已经写入 getPosNext 位置的数据但不取走，直到该位置写入完成才返回，只能由唯一的消费者调用。
+// WithDropAfter 时和 takeAt 一样，等待超过次数就放弃这个位置，槽当作写入了空的数据，返回 nil，之后照常取走
+func (q *DefaultQueue) peekAt(getPosNext uint32) (val interface{}) {
+	cache := &q.carrier[getPosNext&q.capMod]
+	for spins := uint32(0); ; spins++ {
+		readID := cache.readID.Load()
+		writeID := cache.writeID.Load()
+		if q.dropAfter > 0 && spins >= q.dropAfter && getPosNext == readID && getPosNext == writeID &&
+			cache.writeID.CAS(writeID, writeID+q.cap) {
+			return nil
+		}
+		if getPosNext == readID && (readID+q.cap == writeID) {
+			return cache.value
+		}
+		runtime.Gosched()
+	}
+}
+
+// PutAndYield the same as Put, and yield the cpu after a success put,
+// so a waiting consumer may pick up the item sooner, for the latency critical paths.
+// It's only a hint to the scheduler and costs throughput, measure before use,
+// it doesn't help a busy producer which keeps the queue non-empty anyway
+func (q *DefaultQueue) PutAndYield(val interface{}) (ok bool, count uint32) {
+	if ok, count = q.Put(val); ok {
+		runtime.Gosched()
+	}
+	return ok, count
+}
+
+// PutCallback the same as Put, and onConsumed is called once after the item is taken out by a consumer,
+// let the producer know when the item was picked up.
+// Always fail if the item would be written to disk by WithSpillover, the callback can't be saved
+func (q *DefaultQueue) PutCallback(val interface{}, onConsumed func()) (ok bool, count uint32) {
+	return q.put(entry{value: val, x: &extra{done: onConsumed}})
+}
+
+// PutTracked the same as Put, and wg.Add(1) for the item, wg.Done() is called after it's consumed,
+// so the coordinator can wg.Wait() until all the tracked items are processed.
+// Nothing is added to wg if failed, see PutCallback
+func (q *DefaultQueue) PutTracked(val interface{}, wg *sync.WaitGroup) (ok bool, count uint32) {
+	// 先 Add 再放入，否则消费者可能在 Add 之前就 Done 了
+	wg.Add(1)
+	if ok, count = q.put(entry{value: val, x: &extra{done: wg.Done}}); !ok {
+		wg.Done()
+	}
+	return ok, count
+}
+
+// Info a short description of the queue state
+func (q *DefaultQueue) Info() string {
+	info := fmt.Sprintf("cap: %d, count: %d, closed: %v", q.cap, q.Count(), q.closed.Load())
+	if q.id != "" {
+		return "id: " + q.id + ", " + info
+	}
+	return info
+}
+
+// String implement fmt.Stringer, the same as Info with the type name
+func (q *DefaultQueue) String() string {
+	return "queue{" + q.Info() + "}"
+}
+
+// ID the identifier set by WithID, empty if not set
+func (q *DefaultQueue) ID() string {
+	return q.id
+}
+
+// tag 输出信息中队列的称呼，设置了 id 时带上 id
+func (q *DefaultQueue) tag() string {
+	if q.id == "" {
+		return "queue"
+	}
+	return "queue " + q.id
+}
+
+// Capacity the allocated size of queue, always 2's power,
+// at most Capacity - 2 items can be put at the same time
+func (q *DefaultQueue) Capacity() uint32 {
+	return q.cap
+}
+
+// Count the number of items in queue, include the ones reserved but not finished writing
+func (q *DefaultQueue) Count() uint32 {
+	return q.posCount(q.read.Load(), q.write.Load())
+}
+
+// Close mark the queue closed, Put will fail after close,
+// but the items already in queue can still Get, return ErrClosed if closed already
+func (q *DefaultQueue) Close() error {
+	if !q.closed.CAS(false, true) {
+		return ErrClosed
+	}
+	if q.spill != nil {
+		q.spill.close()
+	}
+	if q.name != "" {
+		unregister(q.name, q.self)
+	}
+	return nil
+}
+
+// Reset empty the queue and make it the same as a fresh one,
+// not concurrent safe, must be called when there is no Put/Get running.
+// The slot atomics are reused, no allocation
+func (q *DefaultQueue) Reset() {
+	q.resets.Inc()
+	q.resetRing()
+	if q.spill != nil {
+		q.spill.reset()
+	}
+}
+
+// Warm write every slot and its ids once, so the pages of carrier are resident and in cache,
+// avoid the page faults on the first operations. Optional, leave the queue unchanged,
+// not concurrent safe, best called at startup before any Put/Get
+func (q *DefaultQueue) Warm() {
+	for i := range q.carrier {
+		cache := &q.carrier[i]
+		v := cache.value
+		cache.value = cache
+		cache.value = v
+		cache.writeID.Store(cache.writeID.Load())
+		cache.readID.Store(cache.readID.Load())
+	}
+}
+
+// ClearValues take out and drop all items, the queue is empty after that but the positions keep climbing,
+// cheaper than Reset which renumber all the slots. The items on disk by WithSpillover are kept.
+// Should be called when there is no Put/Get running, return how many dropped
+func (q *DefaultQueue) ClearValues() int {
+	return q.Skip(int(q.cap))
+}
+
+// Compact renumber the read/write positions back to the start and keep the items in FIFO order,
+// so the positions never climb to overflow after a long runtime.
+// Not concurrent safe, must be called when there is no Put/Get running
+func (q *DefaultQueue) Compact() {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	n := q.posCount(read, write)
+	entries := make([]entry, n)
+	var i uint32
+	for ; i < n; i++ {
+		entries[i] = q.carrier[(read+1+i)&q.capMod].entry
+	}
+
+	q.resetRing()
+	for i = 0; i < n; i++ {
+		q.putAt(i+1, entries[i]) // 时间戳不为 0，会保留原来的写入时间
+	}
+	q.write.Store(n)
+}
+
+// resetRing 清空环形队列，所有位置恢复到初始状态
+func (q *DefaultQueue) resetRing() {
+	for i := range q.carrier {
+		q.carrier[i].entry = entry{}
+	}
+
+	var i uint32
+	for ; i < q.cap; i++ {
+		q.carrier[i].readID.Store(q.initID(i))
+		q.carrier[i].writeID.Store(q.initID(i))
+	}
+	q.write.Store(0)
+	q.read.Store(0)
+	q.wasFull.Store(false)
+}
+
+// initID 槽的初始 writeID/readID，读写位置从 1 开始，所以 0 号槽第一次使用的位置是 cap
+func (q *DefaultQueue) initID(i uint32) uint32 {
+	if i == 0 {
+		return q.cap
+	}
+	return i
+}
+
 // 队列中元素的个数，注意读写指标前后位置
+// 溢出回绕后直接相减也是对的，相等时为空，
+// 调用方都是先 Load read 再 Load write，write 只会增加，所以正常情况下 write >= read，
+// 但两次 Load 之间读写位置都在变化：read 读得早，期间又放入又取出了很多，相减可能超过 cap；
+// 和 Reset 并发时 read 是旧的位置，write 已经被置为 0，相减会得到一个接近 2^32 的数。
+// 按有符号数判断后当作空，结果限制在 [0, cap]
 func (q *DefaultQueue) posCount(read, write uint32) uint32 {
-	if write > read {
-		return write - read
+	return posCount(read, write, q.cap)
+}
+
+// posCount 不依赖队列的版本，函数足够小可以被内联到 Put/Get 中
+func posCount(read, write, cap uint32) uint32 {
+	n := write - read
+	if int32(n) < 0 {
+		return 0
+	}
+	if n > cap {
+		return cap
 	}
-	return q.cap - read + write
+	return n
 }
 
-// minRoundNumBy2 round 到 >=N的 最近的2的倍数，
-// example f(3) = 4
-func (q *DefaultQueue) minRoundNumBy2(v uint32) uint32 {
+// minRoundNumBy2 round 到 >=N的 最近的2的幂次，本身是2的幂次时不变，
+// example f(3) = 4, f(8) = 8, f(9) = 16
+func minRoundNumBy2(v uint32) uint32 {
 	if v < MinCap {
 		v = MinCap
 	}
+	// 超过 1<<31 的下一个2的幂次是 1<<32，uint32 放不下，直接用最大的
+	if v > 1<<31 {
+		return 1 << 31
+	}
 
+	v-- // 先减1，本身就是2的幂次时，下面的操作后加1还是自己
 	v |= v >> 1
 	v |= v >> 2
 	v |= v >> 4
 	v |= v >> 8
 	v |= v >> 16
-
-	if ^v == 0 {
-		v-- // --为了防止刚刚好最大值时溢出，上列操作后所有位上都是1，最后 ++ 如果一开始就是占了最大的长度，就会溢出
-	} else {
-		v++
-	}
+	v++
 	return v
 }
@@ -0,0 +1,72 @@
+package queue
+
+/*
+ @File : readonly.go
+ @Description: read only view of a queue for monitoring code
+ @Time : 2026/10/15
+*/
+
+// readable 只读视图需要的方法，DefaultQueue 以及内嵌它的变体队列都实现了
+type readable interface {
+	Count() uint32
+	Capacity() uint32
+	Peek() (val interface{}, ok bool)
+	Snapshot() []interface{}
+	Range(fn func(val interface{}) bool)
+}
+
+// ReadOnlyQueue a view which can only read the queue, no Put/Get,
+// the queue behind it can't be got back, so monitoring code never mutate it by accident
+type ReadOnlyQueue struct {
+	q readable // 零值时为空，所有方法返回零值
+}
+
+// ReadOnly return the read only view of q,
+// ok false if q can't be inspected (such as AdmissionQueue, which doesn't expose its ring), the view is empty then
+func ReadOnly(q Queue) (view ReadOnlyQueue, ok bool) {
+	r, ok := q.(readable)
+	if !ok {
+		return ReadOnlyQueue{}, false
+	}
+	return ReadOnlyQueue{q: r}, true
+}
+
+// Count see DefaultQueue.Count
+func (r ReadOnlyQueue) Count() uint32 {
+	if r.q == nil {
+		return 0
+	}
+	return r.q.Count()
+}
+
+// Capacity see DefaultQueue.Capacity
+func (r ReadOnlyQueue) Capacity() uint32 {
+	if r.q == nil {
+		return 0
+	}
+	return r.q.Capacity()
+}
+
+// Peek see DefaultQueue.Peek
+func (r ReadOnlyQueue) Peek() (val interface{}, ok bool) {
+	if r.q == nil {
+		return nil, false
+	}
+	return r.q.Peek()
+}
+
+// Snapshot see DefaultQueue.Snapshot
+func (r ReadOnlyQueue) Snapshot() []interface{} {
+	if r.q == nil {
+		return nil
+	}
+	return r.q.Snapshot()
+}
+
+// Range see DefaultQueue.Range
+func (r ReadOnlyQueue) Range(fn func(val interface{}) bool) {
+	if r.q == nil {
+		return
+	}
+	r.q.Range(fn)
+}
@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadOnlyHasNoMutators(t *testing.T) {
+	typ := reflect.TypeOf(ReadOnlyQueue{})
+	for _, name := range []string{"Put", "Get", "Puts", "Gets", "Close", "Reset"} {
+		if _, ok := typ.MethodByName(name); ok {
+			t.Fatalf("ReadOnlyQueue has method %s", name)
+		}
+	}
+	if _, ok := interface{}(ReadOnlyQueue{}).(Queue); ok {
+		t.Fatal("ReadOnlyQueue implements Queue")
+	}
+}
+
+func TestReadOnlyDelegates(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.PutSlice(ints(5))
+	r, ok := ReadOnly(q)
+	if !ok {
+		t.Fatal("DefaultQueue can't be read")
+	}
+
+	if r.Count() != 5 || r.Capacity() != q.Capacity() {
+		t.Fatalf("count %d capacity %d, queue has %d/%d", r.Count(), r.Capacity(), q.Count(), q.Capacity())
+	}
+	if val, ok := r.Peek(); !ok || val != 0 {
+		t.Fatalf("peek %v %v", val, ok)
+	}
+	assertSeq(t, r.Snapshot(), 0, 5)
+	var ranged []interface{}
+	r.Range(func(val interface{}) bool {
+		ranged = append(ranged, val)
+		return len(ranged) < 3
+	})
+	assertSeq(t, ranged, 0, 3)
+
+	// 只读视图不会取走数据，原队列的变化能看到
+	if q.Count() != 5 {
+		t.Fatalf("queue count %d after reading the view", q.Count())
+	}
+	q.Get()
+	if val, _ := r.Peek(); val != 1 || r.Count() != 4 {
+		t.Fatalf("view not following the queue: peek %v count %d", val, r.Count())
+	}
+}
+
+func TestReadOnlyUnsupportedQueue(t *testing.T) {
+	// 不能读取的队列返回 false，而不是一个看起来是空队列的视图
+	for _, q := range []Queue{rejectQueue{}, NewAdmissionQueue(8, 0.5)} {
+		if _, ok := ReadOnly(q); ok {
+			t.Fatalf("ReadOnly of %T succeeded", q)
+		}
+	}
+	r, _ := ReadOnly(rejectQueue{})
+	if r.Count() != 0 || r.Capacity() != 0 || r.Snapshot() != nil {
+		t.Fatal("view of an unreadable queue is not empty")
+	}
+	if _, ok := r.Peek(); ok {
+		t.Fatal("peek on an unreadable queue succeeded")
+	}
+	r.Range(func(interface{}) bool {
+		t.Fatal("range called fn on an unreadable queue")
+		return false
+	})
+}
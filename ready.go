@@ -0,0 +1,89 @@
+package queue
+
+/*
+ @File : ready.go
+ @Description: get without spinning on the slot which is reserved but not written yet
+ @Time : 2026/10/15
+*/
+
+import "runtime"
+
+// GetStatus result of GetReady
+type GetStatus int
+
+const (
+	OK              GetStatus = iota // 取到了数据
+	Empty                            // 队列为空
+	NotYetCommitted                  // 队头的位置已经被生产者占了，但是还没写完
+
+	Pending = NotYetCommitted // PeekStatus 中的叫法，同 NotYetCommitted
+)
+
+// UncommittedPolicy what Get does when the head is reserved by a producer but not written yet,
+// see WithUncommittedPolicy
+type UncommittedPolicy int
+
+const (
+	SpinOnUncommitted    UncommittedPolicy = iota // 占位后等待生产者写完，默认
+	EmptyOnUncommitted                            // 不占位，当作空返回，count 为 0
+	PendingOnUncommitted                          // 不占位，返回失败，count 为实际数量，可以用 PeekStatus 确认
+)
+
+// GetReady the same as Get but never spin, return NotYetCommitted at once
+// if the head is reserved by a producer but not written yet, the caller decide whether to retry.
+// The head is checked before reserving it, so NotYetCommitted doesn't lose the item
+func (q *DefaultQueue) GetReady() (val interface{}, status GetStatus) {
+	for {
+		read := q.read.Load()
+		write := q.write.Load()
+		if q.posCount(read, write) < 1 {
+			return nil, Empty
+		}
+
+		getPosNext := read + 1
+		if !q.committed(getPosNext) {
+			return nil, NotYetCommitted
+		}
+		// 占位失败说明被其他消费者取走了，再看下一个
+		if !q.casRead(read, getPosNext) {
+			runtime.Gosched()
+			continue
+		}
+		return q.getAt(getPosNext), OK
+	}
+}
+
+// PeekStatus report the head without taking it: OK with the committed value, Empty,
+// or Pending if the head is reserved by a producer but not written yet,
+// so the event loop can choose between yielding and retrying.
+// Best effort like Peek, the head may be taken by a consumer right after
+func (q *DefaultQueue) PeekStatus() (val interface{}, status GetStatus) {
+	for {
+		read := q.read.Load()
+		write := q.write.Load()
+		if q.posCount(read, write) < 1 {
+			return nil, Empty
+		}
+		if !q.committed(read + 1) {
+			// 队头没写完也可能是刚被消费者取走，read 没变才是真的在等生产者
+			if q.read.Load() == read {
+				return nil, Pending
+			}
+			continue
+		}
+		val, ok := q.peekCommitted(read + 1)
+		if !ok {
+			continue // 读的过程中被取走了，看新的队头
+		}
+		return val, OK
+	}
+}
+
+// committed getPosNext 位置的数据是否已经写完可以读
+func (q *DefaultQueue) committed(getPosNext uint32) bool {
+	cache := &q.carrier[getPosNext&q.capMod]
+	readID := cache.readID.Load()
+	writeID := cache.writeID.Load()
+	// readID 为 getPosNext-1 时是 Peek 正在读，已经写完了，见 loadValue
+	return (getPosNext == readID || getPosNext-1 == readID) && getPosNext+q.cap == writeID
+}
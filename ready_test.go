@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetReady(t *testing.T) {
+	q := newDefaultQueue(8)
+	if val, status := q.GetReady(); status != Empty || val != nil {
+		t.Fatalf("got %v status %d from empty queue, want Empty", val, status)
+	}
+
+	// 队头被生产者占了还没写完
+	token, _ := q.Reserve()
+	q.Put(2)
+	for i := 0; i < 3; i++ {
+		if val, status := q.GetReady(); status != NotYetCommitted || val != nil {
+			t.Fatalf("got %v status %d on a reserved head, want NotYetCommitted", val, status)
+		}
+	}
+	// 没有占位，数据没有丢
+	if q.read.Load() != 0 || q.Count() != 2 {
+		t.Fatalf("read %d count %d, the head was reserved by GetReady", q.read.Load(), q.Count())
+	}
+
+	token.Commit(1)
+	for want := 1; want <= 2; want++ {
+		if val, status := q.GetReady(); status != OK || val != want {
+			t.Fatalf("got %v status %d, want %d OK", val, status, want)
+		}
+	}
+	if _, status := q.GetReady(); status != Empty {
+		t.Fatalf("status %d after taking all, want Empty", status)
+	}
+}
+
+func TestPeekStatus(t *testing.T) {
+	q := newDefaultQueue(8)
+	if val, status := q.PeekStatus(); status != Empty || val != nil {
+		t.Fatalf("peek %v status %d on empty queue, want Empty", val, status)
+	}
+
+	token, _ := q.Reserve()
+	q.Put(2)
+	if val, status := q.PeekStatus(); status != Pending || val != nil {
+		t.Fatalf("peek %v status %d on a mid-commit head, want Pending", val, status)
+	}
+
+	token.Commit(1)
+	// 不取走，多次 Peek 结果一样
+	for i := 0; i < 3; i++ {
+		if val, status := q.PeekStatus(); status != OK || val != 1 {
+			t.Fatalf("peek %v status %d, want 1 OK", val, status)
+		}
+	}
+	if q.Count() != 2 {
+		t.Fatalf("count %d after peeking", q.Count())
+	}
+	q.Get()
+	if val, status := q.PeekStatus(); status != OK || val != 2 {
+		t.Fatalf("peek %v status %d after get, want 2 OK", val, status)
+	}
+	q.Get()
+	if _, status := q.PeekStatus(); status != Empty {
+		t.Fatalf("status %d after taking all, want Empty", status)
+	}
+}
+
+func TestUncommittedPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy UncommittedPolicy
+		count  uint32 // 队头没写完时 Get 返回的数量
+	}{
+		{"empty", EmptyOnUncommitted, 0},
+		{"pending", PendingOnUncommitted, 2},
+	} {
+		q := newDefaultQueue(8, WithUncommittedPolicy(tc.policy))
+		token, _ := q.Reserve()
+		q.Put(2)
+		for i := 0; i < 3; i++ {
+			if val, ok, cnt := q.Get(); ok || val != nil || cnt != tc.count {
+				t.Fatalf("%s: got %v %v count %d on a stalled head, want count %d", tc.name, val, ok, cnt, tc.count)
+			}
+		}
+		// 不占位，队头写完后按顺序取出
+		if q.read.Load() != 0 {
+			t.Fatalf("%s: head reserved by Get", tc.name)
+		}
+		token.Commit(1)
+		for want := 1; want <= 2; want++ {
+			if val, ok, _ := q.Get(); !ok || val != want {
+				t.Fatalf("%s: got %v %v, want %d", tc.name, val, ok, want)
+			}
+		}
+	}
+}
+
+func TestUncommittedPolicySpin(t *testing.T) {
+	q := newDefaultQueue(8) // 默认 SpinOnUncommitted
+	token, _ := q.Reserve()
+	q.Put(2)
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		token.Commit(1)
+	}()
+	// 占住队头等生产者写完
+	if val, ok, _ := q.Get(); !ok || val != 1 {
+		t.Fatalf("got %v %v, want 1 after waiting", val, ok)
+	}
+	if val, ok, _ := q.Get(); !ok || val != 2 {
+		t.Fatalf("got %v %v, want 2", val, ok)
+	}
+}
@@ -0,0 +1,88 @@
+package queue
+
+/*
+ @File : recording.go
+ @Description: debug queue which record every Put/Get for replaying the history
+ @Time : 2026/10/15
+*/
+
+import (
+	"sync"
+	"time"
+)
+
+// RecordHistory how many events RecordingQueue keeps, the older ones are overwritten
+var RecordHistory = 4096
+
+// Event one Put or Get recorded by RecordingQueue
+type Event struct {
+	Op    string      // "put" or "get"
+	Value interface{} // the value put, or the value got
+	OK    bool
+	Count uint32 // the count returned by the operation
+	Time  time.Time
+}
+
+// RecordingQueue record every Put/Get with its value, result and count into an in-memory ring of events.
+// The operation and its record are done under a mutex, so History is exactly the order they happened,
+// a debug tool for concurrency issues, much slower than DefaultQueue
+type RecordingQueue struct {
+	ring *DefaultQueue
+
+	mu     sync.Mutex
+	events []Event
+	next   int  // 下一个事件写入的位置
+	full   bool // events 是否已经写满一轮
+}
+
+// NewRecordingQueue alloc a RecordingQueue keeping the last RecordHistory events
+func NewRecordingQueue(cap uint32) *RecordingQueue {
+	n := RecordHistory
+	if n < 1 {
+		n = 1
+	}
+	return &RecordingQueue{
+		ring:   newDefaultQueue(cap),
+		events: make([]Event, n),
+	}
+}
+
+// Put the same as DefaultQueue.Put, and record it
+func (q *RecordingQueue) Put(val interface{}) (ok bool, count uint32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ok, count = q.ring.Put(val)
+	q.record(Event{Op: "put", Value: val, OK: ok, Count: count})
+	return ok, count
+}
+
+// Get the same as DefaultQueue.Get, and record it
+func (q *RecordingQueue) Get() (val interface{}, ok bool, count uint32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	val, ok, count = q.ring.Get()
+	q.record(Event{Op: "get", Value: val, OK: ok, Count: count})
+	return val, ok, count
+}
+
+// History the recorded events from the oldest to the latest
+func (q *RecordingQueue) History() []Event {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.full {
+		return append([]Event(nil), q.events[:q.next]...)
+	}
+	h := make([]Event, 0, len(q.events))
+	h = append(h, q.events[q.next:]...)
+	return append(h, q.events[:q.next]...)
+}
+
+// record 记录一个事件，调用方持有锁
+func (q *RecordingQueue) record(e Event) {
+	e.Time = time.Now()
+	q.events[q.next] = e
+	q.next++
+	if q.next == len(q.events) {
+		q.next, q.full = 0, true
+	}
+}
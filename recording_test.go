@@ -0,0 +1,88 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRecordingQueueHistory(t *testing.T) {
+	q := NewRecordingQueue(8) // 最多 6 个
+	q.Get()
+	for i := 0; i < 7; i++ {
+		q.Put(i)
+	}
+	q.Get()
+
+	want := []Event{{Op: "get", Value: nil, OK: false, Count: 0}}
+	for i := 0; i < 6; i++ {
+		want = append(want, Event{Op: "put", Value: i, OK: true, Count: uint32(i + 1)})
+	}
+	want = append(want,
+		Event{Op: "put", Value: 6, OK: false, Count: 6},
+		Event{Op: "get", Value: 0, OK: true, Count: 5},
+	)
+	h := q.History()
+	if len(h) != len(want) {
+		t.Fatalf("recorded %d events, want %d: %+v", len(h), len(want), h)
+	}
+	for i, e := range h {
+		if e.Op != want[i].Op || e.Value != want[i].Value || e.OK != want[i].OK || e.Count != want[i].Count {
+			t.Fatalf("event %d is %+v, want %+v", i, e, want[i])
+		}
+		if e.Time.IsZero() || (i > 0 && e.Time.Before(h[i-1].Time)) {
+			t.Fatalf("event %d time %v out of order", i, e.Time)
+		}
+	}
+}
+
+func TestRecordingQueueOverwrite(t *testing.T) {
+	defer func(n int) { RecordHistory = n }(RecordHistory)
+	RecordHistory = 4
+	q := NewRecordingQueue(16)
+	for i := 0; i < 6; i++ {
+		q.Put(i)
+	}
+	// 只保留最新的 4 个，从旧到新
+	h := q.History()
+	if len(h) != 4 {
+		t.Fatalf("kept %d events, want 4", len(h))
+	}
+	for i, e := range h {
+		if e.Value != i+2 {
+			t.Fatalf("event %d is %v, want %d", i, e.Value, i+2)
+		}
+	}
+}
+
+func TestRecordingQueueConcurrent(t *testing.T) {
+	q := NewRecordingQueue(64)
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				q.Put(p*100 + i)
+				q.Get()
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	// 按记录的顺序重放，结果和记录的一致
+	replay := newDefaultQueue(64)
+	for i, e := range q.History() {
+		var ok bool
+		var val interface{}
+		var cnt uint32
+		if e.Op == "put" {
+			val = e.Value
+			ok, cnt = replay.Put(e.Value)
+		} else {
+			val, ok, cnt = replay.Get()
+		}
+		if val != e.Value || ok != e.OK || cnt != e.Count {
+			t.Fatalf("replay event %d: %v %v %d, recorded %+v", i, val, ok, cnt, e)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package queue
+
+/*
+ @File : registry.go
+ @Description: package level registry of live queues, for debug and ops
+ @Time : 2026/10/15
+*/
+
+import "sync"
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Queue)
+)
+
+// Registry return a snapshot of all queues created with WithRegistry and not closed yet
+func Registry() map[string]Queue {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	snapshot := make(map[string]Queue, len(registry))
+	for name, q := range registry {
+		snapshot[name] = q
+	}
+	return snapshot
+}
+
+// register 同名的队列后注册的会覆盖前面的
+func register(name string, q Queue) {
+	registryMu.Lock()
+	registry[name] = q
+	registryMu.Unlock()
+}
+
+// unregister 只删除自己注册的，防止同名覆盖后被前一个队列的 Close 误删
+func unregister(name string, q Queue) {
+	registryMu.Lock()
+	if registry[name] == q {
+		delete(registry, name)
+	}
+	registryMu.Unlock()
+}
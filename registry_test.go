@@ -0,0 +1,49 @@
+package queue
+
+import "testing"
+
+func TestRegistry(t *testing.T) {
+	a := NewQueue(8, WithRegistry("test-registry-a"))
+	b := NewQueue(8, WithRegistry("test-registry-b"))
+	plain := NewQueue(8)
+
+	snapshot := Registry()
+	if snapshot["test-registry-a"] != a || snapshot["test-registry-b"] != b {
+		t.Fatalf("registered queues missing from snapshot: %v", snapshot)
+	}
+	for _, q := range snapshot {
+		if q == plain {
+			t.Fatal("queue created without WithRegistry is registered")
+		}
+	}
+
+	// 快照是复制出来的，修改不影响 registry
+	delete(snapshot, "test-registry-a")
+	if _, ok := Registry()["test-registry-a"]; !ok {
+		t.Fatal("modifying the snapshot changed the registry")
+	}
+
+	a.(*DefaultQueue).Close()
+	snapshot = Registry()
+	if _, ok := snapshot["test-registry-a"]; ok {
+		t.Fatal("closed queue still registered")
+	}
+	if snapshot["test-registry-b"] != b {
+		t.Fatal("closing one queue unregistered another")
+	}
+	b.(*DefaultQueue).Close()
+}
+
+func TestRegistrySameName(t *testing.T) {
+	old := NewQueue(8, WithRegistry("test-registry-same"))
+	cur := NewQueue(8, WithRegistry("test-registry-same"))
+	// 被覆盖的旧队列关闭时不能删掉新注册的
+	old.(*DefaultQueue).Close()
+	if Registry()["test-registry-same"] != cur {
+		t.Fatal("closing the overwritten queue unregistered the new one")
+	}
+	cur.(*DefaultQueue).Close()
+	if _, ok := Registry()["test-registry-same"]; ok {
+		t.Fatal("queue not unregistered on Close")
+	}
+}
@@ -0,0 +1,63 @@
+package queue
+
+/*
+ @File : reserve.go
+ @Description: two-phase put, reserve a position first and commit the value later
+ @Time : 2026/10/15
+*/
+
+// Token a reserved position returned by Reserve, must be committed exactly once.
+// Consumers wait on the position until it's committed, so don't hold it for long
+type Token struct {
+	q   *DefaultQueue
+	pos uint32
+}
+
+// Reserve reserve the next position for a later Commit,
+// May failed if lock slot failed or full or closed, the same as Put
+func (q *DefaultQueue) Reserve() (token *Token, ok bool) {
+	posNext, _, ok := q.reserveOne()
+	if !ok {
+		return nil, false
+	}
+	return &Token{q: q, pos: posNext}, true
+}
+
+// reserveOne 占一个写的位置，关闭、满、磁盘上还有溢出的数据或者占位失败都返回 false，
+// 占到后调用方必须 putAt，否则消费者会一直等待这个位置
+func (q *DefaultQueue) reserveOne() (posNext, cnt uint32, ok bool) {
+	read := q.read.Load()
+	write := q.write.Load()
+
+	cnt = q.posCount(read, write)
+	if q.closed.Load() {
+		return 0, cnt, false
+	}
+	if cnt >= q.usable() || (q.spill != nil && q.spill.len() > 0) {
+		q.onFull(cnt)
+		q.yield()
+		return 0, cnt, false
+	}
+
+	posNext = write + 1
+	if !q.casWrite(write, posNext) {
+		q.yield()
+		return 0, cnt, false
+	}
+	q.track(posNext, "")
+	return posNext, cnt, true
+}
+
+// Commit write val into the reserved position, then consumers can get it
+func (t *Token) Commit(val interface{}) {
+	t.q.putAt(t.pos, entry{value: val})
+	t.q.onPut(1)
+}
+
+// Committed whether the value of the reserved position was published by Commit,
+// still true after the value is consumed
+func (t *Token) Committed() bool {
+	writeID := t.q.carrier[t.pos&t.q.capMod].writeID.Load()
+	// 提交前 writeID 为 pos 或者 pos-cap（上一轮还没写完），提交后至少为 pos+cap，回绕后用有符号差值比较
+	return int32(writeID-t.pos) > 0
+}
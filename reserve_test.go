@@ -0,0 +1,35 @@
+package queue
+
+import "testing"
+
+func TestTokenCommitted(t *testing.T) {
+	for _, base := range []uint32{0, 0xfffffffe} {
+		q := newDefaultQueue(8)
+		advanceTo(q, base)
+		// 先跑一轮，让槽的 id 是上一轮的状态
+		q.PutSlice(ints(6))
+		drainAll(t, q)
+
+		a, ok := q.Reserve()
+		if !ok {
+			t.Fatal("reserve failed")
+		}
+		b, _ := q.Reserve()
+		if a.Committed() || b.Committed() {
+			t.Fatalf("base %#x: committed before Commit", base)
+		}
+		b.Commit(2)
+		if a.Committed() || !b.Committed() {
+			t.Fatalf("base %#x: a %v b %v after committing b", base, a.Committed(), b.Committed())
+		}
+		a.Commit(1)
+		if !a.Committed() {
+			t.Fatalf("base %#x: not committed after Commit", base)
+		}
+		assertSeq(t, drainAll(t, q), 1, 2)
+		// 取走后仍然是已提交
+		if !a.Committed() || !b.Committed() {
+			t.Fatalf("base %#x: not committed after consumed", base)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package queue
+
+/*
+ @File : rotate.go
+ @Description: move the head item to the tail, for round robin over the buffered items
+ @Time : 2026/10/15
+*/
+
+// Rotate take the head item and put it back at the tail, return the value,
+// so calling it repeatedly cycles through the buffered items in order.
+// Must be called from ONLY ONE consumer goroutine, and no Get at the same time.
+// Put from other goroutines is fine as long as the producers CAS the write position,
+// their items are placed before or after the rotated one by timing.
+// The producer of SPMCQueue moves write without CAS and would overwrite the tail reserved here,
+// so Rotate always fails on it.
+// The tail position is reserved before taking the head, so the item is never lost even if producers fill the queue,
+// the queue may hold one item more than usual for a moment.
+// ok false if the queue is empty, the head is an empty item, items are spilled to disk (see WithSpillover),
+// or the queue is a SPMCQueue.
+// The callback of PutCallback is called when the item is rotated
+func (q *DefaultQueue) Rotate() (val interface{}, ok bool) {
+	if q.singleProducer || (q.spill != nil && q.spill.len() > 0) {
+		return nil, false
+	}
+	var read, tail uint32
+	for {
+		read = q.read.Load()
+		write := q.write.Load()
+		cnt := q.posCount(read, write)
+		// 最多比 usable 多用一个槽，预留给 ReserveCapacity 的空间不能用
+		if cnt < 1 || cnt > q.usable() {
+			return nil, false
+		}
+		// 先占队尾，最多比 Put 多用一个槽，这个槽在队头被取走前不会被使用（putAt 会等它被读完）
+		tail = write + 1
+		if q.casWrite(write, tail) { // 不是单生产者，一定是 CAS
+			break
+		}
+		q.yield()
+	}
+
+	// 队尾占到的槽不管写什么都计入 Puts，和 takeAt 计入的 Gets 对应
+	defer q.countPuts(1)
+	getPosNext := read + 1
+	if !q.casRead(read, getPosNext) {
+		// 违反了单消费者的约定，队头被别人取走了，占的队尾只能写 tombstone
+		q.putAt(tail, entry{value: tombstone})
+		return nil, false
+	}
+	e := q.takeAt(getPosNext)
+	if e.value == nil {
+		q.putAt(tail, entry{value: tombstone})
+		return nil, false
+	}
+	if e.x != nil {
+		e.x.done = nil // 取出时已经回调过，附带信息只属于这个数据，可以直接改
+	}
+	q.putAt(tail, e)
+	return e.value, true
+}
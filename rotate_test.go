@@ -0,0 +1,113 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRotate(t *testing.T) {
+	q := newDefaultQueue(8)
+	if _, ok := q.Rotate(); ok {
+		t.Fatal("rotated an empty queue")
+	}
+	q.PutSlice(ints(3))
+	// 反复轮转按顺序循环
+	for i := 0; i < 9; i++ {
+		if val, ok := q.Rotate(); !ok || val != i%3 {
+			t.Fatalf("rotate %d: %v %v, want %d", i, val, ok, i%3)
+		}
+	}
+	assertSeq(t, drainAll(t, q), 0, 3)
+
+	// 满的时候借用多出来的一个槽，数据不丢
+	q.PutSlice(ints(6))
+	if val, ok := q.Rotate(); !ok || val != 0 {
+		t.Fatalf("rotate a full queue: %v %v, want 0", val, ok)
+	}
+	got := drainAll(t, q)
+	assertSeq(t, got[:5], 1, 5)
+	if got[5] != 0 {
+		t.Fatalf("tail %v, want the rotated 0", got[5])
+	}
+}
+
+func TestRotateRejectsSingleProducer(t *testing.T) {
+	q := NewSPMCQueue(8).(*SPMCQueue)
+	q.PutSlice(ints(3))
+	// 生产者不 CAS，占的队尾会被覆盖，直接拒绝
+	if val, ok := q.Rotate(); ok {
+		t.Fatalf("rotated %v on a single producer queue", val)
+	}
+	assertSeq(t, drainAll(t, q.DefaultQueue), 0, 3)
+}
+
+func TestRotateWithProducers(t *testing.T) {
+	q := newDefaultQueue(64)
+	q.PutSlice([]interface{}{-1, -2, -3})
+	const producers, per = 2, 300
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < per; {
+				if ok, _ := q.Put(p*per + i); ok {
+					i++
+				}
+			}
+		}(p)
+	}
+	// 轮转和生产者并发，数据不丢不重
+	seen := map[interface{}]int{}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if val, ok := q.Rotate(); ok && val.(int) >= 0 {
+			// 生产者的数据只取走，不再放回
+			for {
+				if v, ok, _ := q.Get(); ok {
+					seen[v]++
+					break
+				}
+			}
+		}
+	}
+	for _, v := range drainAll(t, q) {
+		seen[v]++
+	}
+	for i := 0; i < producers*per; i++ {
+		if seen[i] != 1 {
+			t.Fatalf("item %d seen %d times", i, seen[i])
+		}
+	}
+	for _, v := range []int{-1, -2, -3} {
+		if seen[v] != 1 {
+			t.Fatalf("rotated item %d seen %d times", v, seen[v])
+		}
+	}
+}
+
+func TestRotateKeepsReserved(t *testing.T) {
+	q := newDefaultQueue(8) // 最多 6 个
+	q.PutSlice(ints(4))
+	// 模拟和 Put 竞争的 ReserveCapacity，预留之后数量已经超过了普通 Put 能用的
+	q.reserved.Add(3)
+	if val, ok := q.Rotate(); ok {
+		t.Fatalf("rotate %v into the reserved space", val)
+	}
+	q.reserved.Sub(3)
+
+	// 没有预留时满的队列仍然可以 Rotate
+	q.PutSlice(ints(2))
+	if val, ok := q.Rotate(); !ok || val != 0 {
+		t.Fatalf("rotate %v %v on a full queue, want 0", val, ok)
+	}
+}
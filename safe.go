@@ -0,0 +1,36 @@
+package queue
+
+/*
+ @File : safe.go
+ @Description: put which never leave a reserved slot uncommitted, even the producer panics
+ @Time : 2026/10/15
+*/
+
+import "fmt"
+
+// SafePut reserve a slot first, then call produce to get the value and put it into the slot.
+// If produce panics, the panic is recovered and a tombstone is put into the reserved slot instead,
+// the consumer skip it as a nil value, so one panic won't stall all the consumers on this slot.
+// err is not nil when panic recovered, ok is false then.
+// Fail as full while there are items on disk by WithSpillover
+func (q *DefaultQueue) SafePut(produce func() interface{}) (ok bool, count uint32, err error) {
+	posNext, cnt, ok := q.reserveOne()
+	if !ok {
+		return false, cnt, nil
+	}
+
+	committed := false
+	defer func() {
+		if r := recover(); r != nil {
+			if !committed {
+				q.putAt(posNext, entry{value: tombstone})
+				q.countPuts(1) // 消费者取走 tombstone 时也计入 Gets
+			}
+			ok, count, err = false, cnt+1, fmt.Errorf("queue: safe put panic: %v", r)
+		}
+	}()
+	q.putAt(posNext, entry{value: produce()})
+	committed = true
+	q.onPut(1)
+	return true, cnt + 1, nil
+}
@@ -0,0 +1,52 @@
+package queue
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSafePutPanic(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.Put(0)
+	ok, _, err := q.SafePut(func() interface{} {
+		panic("boom")
+	})
+	if ok || err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("ok %v err %v, want the recovered panic", ok, err)
+	}
+	if ok, _, err := q.SafePut(func() interface{} { return 2 }); !ok || err != nil {
+		t.Fatalf("safe put after panic: ok %v err %v", ok, err)
+	}
+
+	// 消费者跳过 tombstone 继续，不会卡在 panic 的槽上
+	done := make(chan []interface{})
+	go func() {
+		var got []interface{}
+		for len(got) < 2 {
+			if val, ok, _ := q.Get(); ok {
+				got = append(got, val)
+			}
+		}
+		done <- got
+	}()
+	got := <-done
+	if got[0] != 0 || got[1] != 2 {
+		t.Fatalf("got %v, want [0 2]", got)
+	}
+	if q.Count() != 0 {
+		t.Fatalf("count %d, want 0", q.Count())
+	}
+}
+
+func TestSafePutFull(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.PutSlice(ints(6))
+	called := false
+	ok, cnt, err := q.SafePut(func() interface{} {
+		called = true
+		return 1
+	})
+	if ok || err != nil || cnt != 6 || called {
+		t.Fatalf("ok %v count %d err %v called %v on full queue", ok, cnt, err, called)
+	}
+}
@@ -0,0 +1,87 @@
+package queue
+
+/*
+ @File : scheduler.go
+ @Description: dequeue across several tenant queues by weighted round-robin
+ @Time : 2026/10/15
+*/
+
+import (
+	"sort"
+	"sync"
+)
+
+// Scheduler dequeue across the tenant queues by weighted round-robin,
+// a tenant with weight 3 get three items in a row before the next tenant, empty tenants are skipped.
+// Put directly into the tenant queues, the Scheduler only takes
+type Scheduler struct {
+	mu      sync.Mutex
+	tenants []schedTenant
+	cur     int // 当前轮到的租户
+	credit  int // 当前租户这一轮还能取几个
+}
+
+type schedTenant struct {
+	name   string
+	q      Queue
+	weight int
+}
+
+// NewScheduler alloc a Scheduler over queues, weights is the weight of each tenant,
+// the tenant missing in weights or with weight less than 1 has weight 1.
+// Tenants are visited in the order of name
+func NewScheduler(queues map[string]Queue, weights map[string]int) *Scheduler {
+	s := &Scheduler{tenants: make([]schedTenant, 0, len(queues))}
+	for name, q := range queues {
+		w := weights[name]
+		if w < 1 {
+			w = 1
+		}
+		s.tenants = append(s.tenants, schedTenant{name: name, q: q, weight: w})
+	}
+	// map 的遍历顺序是随机的，按名字排序让调度顺序固定
+	sort.Slice(s.tenants, func(i, j int) bool {
+		return s.tenants[i].name < s.tenants[j].name
+	})
+	return s
+}
+
+// Get get the next item by weighted round-robin, return which tenant it comes from,
+// ok false if all the tenants are empty
+func (s *Scheduler) Get() (tenant string, val interface{}, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < len(s.tenants); i++ {
+		t := &s.tenants[s.cur]
+		if s.credit <= 0 {
+			s.credit = t.weight
+		}
+		if val, ok = getOrEmpty(t.q); ok {
+			s.credit--
+			if s.credit == 0 {
+				s.next()
+			}
+			return t.name, val, true
+		}
+		// 空的租户跳过，剩下的次数作废
+		s.next()
+	}
+	return "", nil, false
+}
+
+// next 轮到下一个租户
+func (s *Scheduler) next() {
+	s.cur = (s.cur + 1) % len(s.tenants)
+	s.credit = 0
+}
+
+// getOrEmpty 占位失败时重试，直到取到数据或者队列为空
+func getOrEmpty(q Queue) (val interface{}, ok bool) {
+	for {
+		val, ok, cnt := q.Get()
+		if ok || cnt == 0 {
+			return val, ok
+		}
+	}
+}
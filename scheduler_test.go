@@ -0,0 +1,70 @@
+package queue
+
+import "testing"
+
+func TestSchedulerWeightedRatio(t *testing.T) {
+	queues := map[string]Queue{}
+	for _, name := range []string{"a", "b", "c"} {
+		q := newDefaultQueue(1024)
+		q.PutSlice(ints(1000))
+		queues[name] = q
+	}
+	s := NewScheduler(queues, map[string]int{"a": 3, "b": 2, "c": 1})
+
+	got := map[string]int{}
+	next := map[string]int{}
+	for i := 0; i < 1200; i++ {
+		tenant, val, ok := s.Get()
+		if !ok {
+			t.Fatalf("get %d failed", i)
+		}
+		// 同一个租户内保持 FIFO
+		if val != next[tenant] {
+			t.Fatalf("tenant %s got %v, want %d", tenant, val, next[tenant])
+		}
+		next[tenant]++
+		got[tenant]++
+	}
+	if got["a"] != 600 || got["b"] != 400 || got["c"] != 200 {
+		t.Fatalf("dequeues %v, want 3:2:1 of 1200", got)
+	}
+}
+
+func TestSchedulerSkipsEmptyTenants(t *testing.T) {
+	a, b, c := newDefaultQueue(64), newDefaultQueue(64), newDefaultQueue(64)
+	a.PutSlice(ints(2))
+	c.PutSlice(ints(10))
+	s := NewScheduler(map[string]Queue{"a": a, "b": b, "c": c}, map[string]int{"a": 3, "b": 5})
+
+	var order []string
+	for {
+		tenant, _, ok := s.Get()
+		if !ok {
+			break
+		}
+		order = append(order, tenant)
+	}
+	// a 只有 2 个，b 为空跳过，c 没有设置权重按 1
+	want := []string{"a", "a", "c", "c", "c", "c", "c", "c", "c", "c", "c", "c"}
+	if len(order) != len(want) {
+		t.Fatalf("order %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order %v, want %v", order, want)
+		}
+	}
+
+	// 都空了之后再放入，继续调度
+	b.Put("x")
+	if tenant, val, ok := s.Get(); !ok || tenant != "b" || val != "x" {
+		t.Fatalf("get %s %v %v after refill", tenant, val, ok)
+	}
+}
+
+func TestSchedulerNoTenants(t *testing.T) {
+	s := NewScheduler(nil, nil)
+	if _, _, ok := s.Get(); ok {
+		t.Fatal("get from a scheduler without tenants succeeded")
+	}
+}
@@ -0,0 +1,29 @@
+package queue
+
+/*
+ @File : seq.go
+ @Description: put/get with a global sequence number for each item
+ @Time : 2026/10/15
+*/
+
+// PutSeq the same as Put, and assign a global sequence to the item, return it.
+// The sequences of all success PutSeq are contiguous from 1, a failed PutSeq doesn't take one.
+// The sequence is taken after the position is reserved, with a single producer they are in the queue order,
+// with concurrent producers two neighbouring items may swap.
+// Fail as full while there are items on disk by WithSpillover
+func (q *DefaultQueue) PutSeq(val interface{}) (seq uint64, ok bool) {
+	posNext, _, ok := q.reserveOne()
+	if !ok {
+		return 0, false
+	}
+	seq = q.seqGen.Inc()
+	q.putAt(posNext, entry{value: val, x: &extra{seq: seq}})
+	q.onPut(1)
+	return seq, true
+}
+
+// GetSeq the same as Get, and return the sequence assigned by PutSeq, 0 if the item was not put by PutSeq
+func (q *DefaultQueue) GetSeq() (val interface{}, seq uint64, ok bool) {
+	e, ok, _ := q.getEntry()
+	return e.value, e.seq(), ok
+}
@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPutSeqContiguousAndMatched(t *testing.T) {
+	q := newDefaultQueue(8)
+	var want uint64 = 1
+	for round := 0; round < 10; round++ {
+		seqs := map[interface{}]uint64{}
+		for i := 0; ; i++ {
+			seq, ok := q.PutSeq(i)
+			if !ok {
+				break // 满了失败不占序号
+			}
+			if seq != want {
+				t.Fatalf("round %d: seq %d, want %d", round, seq, want)
+			}
+			seqs[i] = seq
+			want++
+		}
+		for len(seqs) > 0 {
+			val, seq, ok := q.GetSeq()
+			if !ok {
+				t.Fatalf("round %d: %d items left", round, len(seqs))
+			}
+			if seqs[val] != seq {
+				t.Fatalf("round %d: item %v got seq %d, put with %d", round, val, seq, seqs[val])
+			}
+			delete(seqs, val)
+		}
+	}
+
+	// 普通 Put 的数据序号为 0
+	q.Put("plain")
+	if _, seq, ok := q.GetSeq(); !ok || seq != 0 {
+		t.Fatalf("plain put got seq %d %v", seq, ok)
+	}
+}
+
+func TestPutSeqConcurrentNoGap(t *testing.T) {
+	q := newDefaultQueue(64)
+	const producers, per = 4, 2000
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < per; {
+				if _, ok := q.PutSeq(p*per + i); ok {
+					i++
+				}
+			}
+		}(p)
+	}
+
+	seen := make([]bool, producers*per+1)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for got := 0; got < producers*per; {
+		_, seq, ok := q.GetSeq()
+		if !ok {
+			continue
+		}
+		if seq == 0 || seq > producers*per || seen[seq] {
+			t.Fatalf("bad or duplicate seq %d", seq)
+		}
+		seen[seq] = true
+		got++
+	}
+	<-done
+	for s := 1; s <= producers*per; s++ {
+		if !seen[s] {
+			t.Fatalf("seq %d missing", s)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package queue
+
+/*
+ @File : sequenced.go
+ @Description: queue which return items in the exact order of a global sequence across all producers
+ @Time : 2026/10/15
+*/
+
+import "sync"
+
+// SequencedQueue give each Put a strictly increasing global sequence and Get return items exactly in that order,
+// with no gap, even across many producers.
+// Two producers may commit in the other order than their sequences (see PutSeq),
+// Get buffers the early ones until the expected sequence comes, so Get is serialized by a mutex,
+// the buffer holds at most about as many items as the concurrent producers
+type SequencedQueue struct {
+	ring *DefaultQueue
+
+	mu      sync.Mutex
+	next    uint64                 // 下一个应该返回的序号
+	pending map[uint64]interface{} // 序号靠后先取到的数据
+}
+
+// NewSequencedQueue alloc a SequencedQueue
+func NewSequencedQueue(cap uint32) *SequencedQueue {
+	return &SequencedQueue{
+		ring:    newDefaultQueue(cap),
+		next:    1,
+		pending: make(map[uint64]interface{}),
+	}
+}
+
+// Put May failed if lock slot failed or full, a failed Put doesn't take a sequence.
+// nil is always rejected, Get can't tell a nil item from nothing and would wait for its sequence forever
+func (q *SequencedQueue) Put(val interface{}) (ok bool, count uint32) {
+	if _, ok = q.PutSeq(val); !ok {
+		return false, q.Count()
+	}
+	return true, q.Count()
+}
+
+// PutSeq the same as Put, and return the sequence of val
+func (q *SequencedQueue) PutSeq(val interface{}) (seq uint64, ok bool) {
+	if val == nil {
+		return 0, false
+	}
+	return q.ring.PutSeq(val)
+}
+
+// Get get the item with the next sequence, ok false if it's not available yet,
+// count is the number of items in ring and buffered
+func (q *SequencedQueue) Get() (val interface{}, ok bool, count uint32) {
+	val, _, ok = q.GetSeq()
+	return val, ok, q.Count()
+}
+
+// GetSeq the same as Get, and return the sequence of val
+func (q *SequencedQueue) GetSeq() (val interface{}, seq uint64, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if val, ok = q.pending[q.next]; ok {
+			delete(q.pending, q.next)
+			return q.take(val)
+		}
+		v, s, got := q.ring.GetSeq()
+		if !got {
+			// 环形队列里没有了，下一个序号的数据还没有提交
+			if q.ring.Count() == 0 {
+				return nil, 0, false
+			}
+			continue
+		}
+		if s == q.next {
+			return q.take(v)
+		}
+		q.pending[s] = v
+	}
+}
+
+// take 返回当前序号的数据，序号前进，调用方持有锁
+func (q *SequencedQueue) take(val interface{}) (interface{}, uint64, bool) {
+	seq := q.next
+	q.next++
+	return val, seq, true
+}
+
+// Count the number of items in ring and buffered by Get
+func (q *SequencedQueue) Count() uint32 {
+	q.mu.Lock()
+	n := uint32(len(q.pending))
+	q.mu.Unlock()
+	return q.ring.Count() + n
+}
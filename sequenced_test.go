@@ -0,0 +1,120 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+)
+
+// putAllSeq producers 个生产者各放入 per 个数据，放满时重试
+func putAllSeq(q *SequencedQueue, producers, per int) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < per; {
+				if ok, _ := q.Put(p*per + i); ok {
+					i++
+				}
+			}
+		}(p)
+	}
+	return &wg
+}
+
+func TestSequencedQueueOrdered(t *testing.T) {
+	q := NewSequencedQueue(16)
+	const producers, per = 8, 1000
+	wg := putAllSeq(q, producers, per)
+
+	// 单消费者看到的序号从 1 开始连续，没有空缺
+	seen := map[interface{}]bool{}
+	for want := uint64(1); want <= producers*per; {
+		val, seq, ok := q.GetSeq()
+		if !ok {
+			continue
+		}
+		if seq != want {
+			t.Fatalf("got seq %d, want %d", seq, want)
+		}
+		if seen[val] {
+			t.Fatalf("item %v got twice", val)
+		}
+		seen[val] = true
+		want++
+	}
+	wg.Wait()
+	if q.Count() != 0 {
+		t.Fatalf("count %d after all", q.Count())
+	}
+}
+
+func TestSequencedQueueManyConsumers(t *testing.T) {
+	q := NewSequencedQueue(16)
+	const producers, consumers, per = 4, 3, 1000
+	wg := putAllSeq(q, producers, per)
+
+	var mu sync.Mutex
+	got := make([]bool, producers*per+1)
+	var cwg sync.WaitGroup
+	var left int64 = producers * per
+	for c := 0; c < consumers; c++ {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			var last uint64
+			for {
+				mu.Lock()
+				if left == 0 {
+					mu.Unlock()
+					return
+				}
+				mu.Unlock()
+				_, seq, ok := q.GetSeq()
+				if !ok {
+					continue
+				}
+				// 每个消费者看到的序号递增
+				if seq <= last {
+					t.Errorf("seq %d after %d", seq, last)
+					return
+				}
+				last = seq
+				mu.Lock()
+				if got[seq] {
+					t.Errorf("seq %d got twice", seq)
+				}
+				got[seq] = true
+				left--
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	cwg.Wait()
+	for s := 1; s <= producers*per; s++ {
+		if !got[s] {
+			t.Fatalf("seq %d missing", s)
+		}
+	}
+}
+
+func TestSequencedQueueRejectNil(t *testing.T) {
+	q := NewSequencedQueue(8)
+	if ok, _ := q.Put(nil); ok {
+		t.Fatal("put nil succeeded")
+	}
+	if _, ok := q.PutSeq(nil); ok {
+		t.Fatal("put seq nil succeeded")
+	}
+	// 被拒绝的 nil 不占序号，后面的数据照常取到，不会一直等
+	if seq, ok := q.PutSeq(1); !ok || seq != 1 {
+		t.Fatalf("put seq %d %v, want 1", seq, ok)
+	}
+	if val, seq, ok := q.GetSeq(); !ok || val != 1 || seq != 1 {
+		t.Fatalf("get %v seq %d %v, want 1 seq 1", val, seq, ok)
+	}
+	if _, _, ok := q.GetSeq(); ok {
+		t.Fatal("get from an empty queue succeeded")
+	}
+}
@@ -0,0 +1,231 @@
+package queue
+
+/*
+ @File : spill.go
+ @Description: disk backed overflow store for WithSpillover,
+               items are written into segment files by gob, and read back in order
+ @Time : 2026/10/15
+*/
+
+import (
+	"encoding/gob"
+	"os"
+	"runtime"
+	"sync"
+
+	"go.uber.org/atomic"
+)
+
+// spillSegment 每个段文件最多保存的数据个数，写满后换一个新文件，读完的文件删除
+const spillSegment = 1024
+
+type spillSeg struct {
+	name string
+	n    int // 段中数据的个数
+}
+
+// spillItem gob 不能直接编码 interface{}，包一层
+type spillItem struct {
+	Val interface{}
+}
+
+type spillStore struct {
+	mu    sync.Mutex
+	dir   string
+	count *atomic.Uint64 // 还没有搬回环形队列的个数，包括 head
+
+	segs []spillSeg // 已经写完可以读的段，按先后顺序
+
+	w    *os.File // 正在写的段
+	enc  *gob.Encoder
+	wseg spillSeg
+
+	r     *os.File // 正在读的段
+	dec   *gob.Decoder
+	rseg  spillSeg
+	rleft int // 正在读的段还剩的个数
+
+	head    interface{} // 已经读出来但是环形队列放不下，下次优先使用
+	hasHead bool
+}
+
+func newSpillStore(dir string) *spillStore {
+	return &spillStore{
+		dir:   dir,
+		count: atomic.NewUint64(0),
+	}
+}
+
+// len 磁盘上还有多少数据，不加锁
+func (s *spillStore) len() uint64 {
+	return s.count.Load()
+}
+
+// push 写入一个数据到磁盘的末尾
+func (s *spillStore) push(val interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.w == nil {
+		if err := os.MkdirAll(s.dir, 0o755); err != nil {
+			return err
+		}
+		f, err := os.CreateTemp(s.dir, "quequ-*.spill")
+		if err != nil {
+			return err
+		}
+		s.w = f
+		s.enc = gob.NewEncoder(f)
+		s.wseg = spillSeg{name: f.Name()}
+	}
+	if err := s.enc.Encode(&spillItem{Val: val}); err != nil {
+		return err
+	}
+	s.wseg.n++
+	s.count.Inc()
+	if s.wseg.n >= spillSegment {
+		s.seal()
+	}
+	return nil
+}
+
+// seal 结束当前写的段，之后才可以读，调用方持有锁
+func (s *spillStore) seal() {
+	if s.w == nil {
+		return
+	}
+	s.w.Close()
+	if s.wseg.n > 0 {
+		s.segs = append(s.segs, s.wseg)
+	} else {
+		os.Remove(s.wseg.name)
+	}
+	s.w, s.enc = nil, nil
+}
+
+// pop 按顺序读出一个数据，调用方持有锁，成功放入环形队列后由调用方减少 count
+func (s *spillStore) pop() (val interface{}, ok bool) {
+	if s.hasHead {
+		val, s.head, s.hasHead = s.head, nil, false
+		return val, true
+	}
+	for {
+		if s.dec == nil {
+			if len(s.segs) == 0 {
+				s.seal() // 读追上了写，把正在写的段也结束掉
+			}
+			if len(s.segs) == 0 {
+				return nil, false
+			}
+			s.rseg = s.segs[0]
+			s.segs = s.segs[1:]
+			f, err := os.Open(s.rseg.name)
+			if err != nil {
+				// 文件没了，这一段的数据只能丢掉
+				s.count.Sub(uint64(s.rseg.n))
+				continue
+			}
+			s.r = f
+			s.dec = gob.NewDecoder(f)
+			s.rleft = s.rseg.n
+		}
+
+		var item spillItem
+		if err := s.dec.Decode(&item); err != nil {
+			// 文件损坏，剩下的数据读不出来了
+			s.count.Sub(uint64(s.rleft))
+			s.closeReader()
+			continue
+		}
+		s.rleft--
+		if s.rleft == 0 {
+			s.closeReader()
+		}
+		return item.Val, true
+	}
+}
+
+// unpop 读出来的数据环形队列放不下，放回去下次再用
+func (s *spillStore) unpop(val interface{}) {
+	s.head, s.hasHead = val, true
+}
+
+// closeReader 读完的段直接删除
+func (s *spillStore) closeReader() {
+	s.r.Close()
+	os.Remove(s.rseg.name)
+	s.r, s.dec, s.rleft = nil, nil, 0
+}
+
+// reset 丢弃磁盘上的所有数据
+func (s *spillStore) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dec != nil {
+		s.closeReader()
+	}
+	s.seal()
+	for _, seg := range s.segs {
+		os.Remove(seg.name)
+	}
+	s.segs = nil
+	s.head, s.hasHead = nil, false
+	s.count.Store(0)
+}
+
+// close 队列关闭后不会再写入，结束正在写的段释放文件句柄，磁盘上没有数据时删除所有的段文件，
+// 还有数据时它们仍然可以 Get，读完的段文件会被删除
+func (s *spillStore) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seal()
+	if s.count.Load() == 0 && !s.hasHead {
+		if s.dec != nil {
+			s.closeReader()
+		}
+		for _, seg := range s.segs {
+			os.Remove(seg.name)
+		}
+		s.segs = nil
+	}
+}
+
+// refill 把磁盘上的数据按顺序搬回环形队列，直到环形队列满或者磁盘上没有数据
+func (q *DefaultQueue) refill() {
+	s := q.spill
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		val, ok := s.pop()
+		if !ok {
+			return
+		}
+		if !q.putRing(val) {
+			s.unpop(val)
+			return
+		}
+		s.count.Dec()
+	}
+}
+
+// putRing 直接放入环形队列，占位失败会重试，只有满了才返回 false。
+// 在消费者的 Get 中调用，单生产者的队列也要和生产者 CAS 竞争，不能用 casWrite 的 Store
+func (q *DefaultQueue) putRing(val interface{}) bool {
+	for {
+		read := q.read.Load()
+		write := q.write.Load()
+		if q.posCount(read, write) >= q.usable() {
+			return false
+		}
+		posNext := write + 1
+		if q.write.CAS(write, posNext) {
+			q.putAt(posNext, entry{value: val})
+			q.countPuts(1)
+			return true
+		}
+		runtime.Gosched()
+	}
+}
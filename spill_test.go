@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestSpilloverFIFO(t *testing.T) {
+	dir := t.TempDir()
+	q := newDefaultQueue(8, WithSpillover(dir))
+
+	// 远超过环形队列的容量，跨过几个段文件
+	const n = 3*spillSegment + 10
+	for i := 0; i < n; i++ {
+		if ok, _ := q.Put(i); !ok {
+			t.Fatalf("put %d failed", i)
+		}
+	}
+	if got := q.spill.len(); got != n-6 {
+		t.Fatalf("%d items on disk, want %d", got, n-6)
+	}
+
+	// 取一半再放一些，溢出的数据和之后放入的数据都保持顺序
+	var got []interface{}
+	for len(got) < n/2 {
+		if val, ok, _ := q.Get(); ok {
+			got = append(got, val)
+		}
+	}
+	for i := n; i < n+100; i++ {
+		if ok, _ := q.Put(i); !ok {
+			t.Fatalf("put %d failed", i)
+		}
+	}
+	for {
+		val, ok, cnt := q.Get()
+		if ok {
+			got = append(got, val)
+		} else if cnt == 0 && q.spill.len() == 0 {
+			break
+		}
+	}
+	assertSeq(t, got, 0, n+100)
+
+	// 读完的段文件都删除了
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("%d spill files left after drain", len(files))
+	}
+}
+
+func TestSpilloverSingleProducer(t *testing.T) {
+	// 消费者 refill 和唯一的生产者竞争写的位置，不能互相覆盖
+	q := NewSPMCQueue(8, WithSpillover(t.TempDir()))
+	const n = 5000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; {
+			if ok, _ := q.Put(i); ok {
+				i++
+			}
+		}
+	}()
+
+	seen := make([]int, n)
+	var mu sync.Mutex
+	left := n
+	for c := 0; c < 4; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				done := left == 0
+				mu.Unlock()
+				if done {
+					return
+				}
+				if val, ok, _ := q.Get(); ok {
+					mu.Lock()
+					seen[val.(int)]++
+					left--
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for v, c := range seen {
+		if c != 1 {
+			t.Fatalf("item %d got %d times", v, c)
+		}
+	}
+}
+
+func TestSpilloverClose(t *testing.T) {
+	dir := t.TempDir()
+	q := newDefaultQueue(8, WithSpillover(dir))
+	for i := 0; i < 20; i++ {
+		q.Put(i)
+	}
+	q.Close()
+	if ok, _ := q.Put(20); ok {
+		t.Fatal("put after close")
+	}
+
+	// 关闭后磁盘上的数据仍然可以取，取完后文件都删除
+	var got []interface{}
+	for {
+		val, ok, cnt := q.Get()
+		if ok {
+			got = append(got, val)
+		} else if cnt == 0 && q.spill.len() == 0 {
+			break
+		}
+	}
+	assertSeq(t, got, 0, 20)
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("%d spill files left after close and drain", len(files))
+	}
+
+	// 没有溢出数据时关闭，不留下任何文件
+	dir = t.TempDir()
+	q = newDefaultQueue(8, WithSpillover(dir))
+	for i := 0; i < 10; i++ {
+		q.Put(i)
+	}
+	drainAll(t, q)
+	for q.spill.len() > 0 {
+		drainAll(t, q)
+		q.Get()
+	}
+	q.Close()
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("%d spill files left after close", len(files))
+	}
+}
@@ -0,0 +1,24 @@
+package queue
+
+/*
+ @File : spmc.go
+ @Description: bounded queue for only one producer and many consumers,
+               the producer side move write without CAS
+ @Time : 2026/10/15
+*/
+
+// SPMCQueue single producer multi consumer queue,
+// Get is the same as DefaultQueue, can be called from many goroutines,
+// Put (and other produce methods) must be called from ONLY ONE goroutine at the same time,
+// otherwise items will be overwritten
+type SPMCQueue struct {
+	*DefaultQueue
+}
+
+// NewSPMCQueue alloc a SPMCQueue, see SPMCQueue for the single producer requirement
+func NewSPMCQueue(cap uint32, opts ...Option) Queue {
+	q := &SPMCQueue{DefaultQueue: newDefaultQueue(cap, opts...)}
+	q.singleProducer = true // 生产者移动 write 不需要 CAS
+	q.registerAs(q)
+	return q
+}
@@ -0,0 +1,19 @@
+package queue
+
+import "testing"
+
+func TestSPMCNoLoss(t *testing.T) {
+	produceConsume(t, NewSPMCQueue(64), 1, 8, 10000)
+}
+
+func BenchmarkSPMC(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		produceConsume(b, NewSPMCQueue(1024), 1, 8, 8000)
+	}
+}
+
+func BenchmarkSPMCDefault(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		produceConsume(b, NewQueue(1024), 1, 8, 8000)
+	}
+}
@@ -0,0 +1,98 @@
+package queue
+
+/*
+ @File : stats.go
+ @Description: runtime statistics of the queue, need WithStats
+ @Time : 2026/10/15
+*/
+
+import (
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// QueueMetrics the cumulative counters collected with WithStats
+type QueueMetrics struct {
+	Puts  uint64 // 写入的数据个数
+	Gets  uint64 // 取走的数据个数
+	Full  uint64 // 因为满失败的 Put 次数
+	Empty uint64 // 因为空失败的 Get 次数
+}
+
+// metrics 累计计数，WithStats 时才更新
+type metrics struct {
+	puts, gets, full, empty *atomic.Uint64
+	putRetries, getRetries  *atomic.Uint64 // 占位 CAS 失败的次数
+}
+
+func newMetrics() metrics {
+	return metrics{
+		puts:  atomic.NewUint64(0),
+		gets:  atomic.NewUint64(0),
+		full:  atomic.NewUint64(0),
+		empty: atomic.NewUint64(0),
+
+		putRetries: atomic.NewUint64(0),
+		getRetries: atomic.NewUint64(0),
+	}
+}
+
+// AvgGetInterval the average interval between two items taken out, updated by EWMA on each one,
+// a long interval while consumers are busy calling Get means they are starved by producers.
+// 0 if nothing is taken yet or no WithStats/WithRateTracking
+func (q *DefaultQueue) AvgGetInterval() time.Duration {
+	return time.Duration(q.getInterval.Load())
+}
+
+// count 累加 QueueMetrics 中的一个计数，持读锁，累加之间不互相阻塞，只和读出所有计数互斥
+func (q *DefaultQueue) count(c *atomic.Uint64, n uint64) {
+	q.statsMu.RLock()
+	c.Add(n)
+	q.statsMu.RUnlock()
+}
+
+// countPuts 统计写入的数据个数，由 putAt 的调用方统计，Compact 这样搬动已有数据的不算
+func (q *DefaultQueue) countPuts(n uint32) {
+	if q.stats {
+		q.count(q.metrics.puts, uint64(n))
+	}
+}
+
+// StatsEnabled whether the queue is created with WithStats, Stats is all 0 otherwise
+func (q *DefaultQueue) StatsEnabled() bool {
+	return q.stats
+}
+
+// Stats the cumulative counters since created or the last SnapshotAndResetStats, all 0 without WithStats.
+// All the counters are read at the same instant, see SnapshotAndResetStats
+func (q *DefaultQueue) Stats() QueueMetrics {
+	q.statsMu.Lock()
+	defer q.statsMu.Unlock()
+	m := q.metrics
+	return QueueMetrics{Puts: m.puts.Load(), Gets: m.gets.Load(), Full: m.full.Load(), Empty: m.empty.Load()}
+}
+
+// SnapshotAndResetStats return the counters and reset them to 0, for the exporters computing per-interval rates.
+// The counters are updated under a read lock and swapped under the write lock, so all of them are
+// swapped at the same instant, each operation is counted in exactly one snapshot.
+// The price is that WithStats costs a lock of the counters on every Put/Get
+func (q *DefaultQueue) SnapshotAndResetStats() QueueMetrics {
+	q.statsMu.Lock()
+	defer q.statsMu.Unlock()
+	m := q.metrics
+	return QueueMetrics{Puts: m.puts.Swap(0), Gets: m.gets.Swap(0), Full: m.full.Swap(0), Empty: m.empty.Swap(0)}
+}
+
+// ContentionStats how many times Put/Get failed to lock the position because of other producers/consumers,
+// since created or the last ResetContentionStats, all 0 without WithStats.
+// High values mean the queue is too small for the concurrency, or too many goroutines on one side
+func (q *DefaultQueue) ContentionStats() (putRetries, getRetries uint64) {
+	return q.metrics.putRetries.Load(), q.metrics.getRetries.Load()
+}
+
+// ResetContentionStats reset the counters of ContentionStats to 0
+func (q *DefaultQueue) ResetContentionStats() {
+	q.metrics.putRetries.Store(0)
+	q.metrics.getRetries.Store(0)
+}
@@ -0,0 +1,225 @@
+package queue
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAvgGetInterval(t *testing.T) {
+	q := newDefaultQueue(8, WithStats())
+	if d := q.AvgGetInterval(); d != 0 {
+		t.Fatalf("interval %v before any get", d)
+	}
+	const cadence = 2 * time.Millisecond
+	measureRate(q, 40, cadence)
+	// Sleep 只会多睡不会少睡，上限给调度留足余量
+	if d := q.AvgGetInterval(); d < cadence*3/4 || d > cadence*10 {
+		t.Fatalf("average interval %v with a cadence of %v", d, cadence)
+	}
+
+	// 间隔变长后平均值跟着上升
+	before := q.AvgGetInterval()
+	measureRate(q, 20, 4*cadence)
+	if d := q.AvgGetInterval(); d <= before {
+		t.Fatalf("average interval %v didn't grow from %v after slower gets", d, before)
+	}
+}
+
+func TestAvgGetIntervalNeedsStats(t *testing.T) {
+	q := newDefaultQueue(8)
+	measureRate(q, 5, time.Millisecond)
+	if d := q.AvgGetInterval(); d != 0 {
+		t.Fatalf("interval %v without WithStats", d)
+	}
+}
+
+func TestSnapshotAndResetStats(t *testing.T) {
+	q := newDefaultQueue(8, WithStats())
+	q.PutSlice(ints(6))
+	q.Put(-1) // 满了
+	q.Get()
+	q.Get()
+	want := QueueMetrics{Puts: 6, Gets: 2, Full: 1}
+	if m := q.Stats(); m != want {
+		t.Fatalf("stats %+v, want %+v", m, want)
+	}
+	if m := q.SnapshotAndResetStats(); m != want {
+		t.Fatalf("snapshot %+v, want %+v", m, want)
+	}
+	if m := q.Stats(); m != (QueueMetrics{}) {
+		t.Fatalf("stats %+v after reset, want all 0", m)
+	}
+
+	// 之后的快照只有新的操作
+	q.Put(1)
+	drainAll(t, q)
+	q.Get()
+	want = QueueMetrics{Puts: 1, Gets: 5, Empty: 1}
+	if m := q.SnapshotAndResetStats(); m != want {
+		t.Fatalf("second snapshot %+v, want %+v", m, want)
+	}
+}
+
+func TestStatsCountEachPutPath(t *testing.T) {
+	q := newDefaultQueue(16, WithStats())
+	q.Put(0)
+	q.Puts(ints(2))
+	q.PutFast(0)
+	q.CompareAndPut(q.Count(), 0)
+	q.PutSeq(0)
+	q.SafePut(func() interface{} { return 0 })
+	token, _ := q.Reserve()
+	token.Commit(0)
+	r, _ := q.ReserveCapacity(1)
+	r.Put(0)
+	q.Rotate()
+	const puts = 10
+	if m := q.Stats(); m.Puts != puts || m.Gets != 1 {
+		t.Fatalf("stats %+v, want %d puts and 1 get of Rotate", m, puts)
+	}
+
+	// Compact 只是搬动已有的数据，不算写入
+	q.Get()
+	q.Compact()
+	if m := q.Stats(); m.Puts != puts || m.Gets != 2 {
+		t.Fatalf("stats %+v after Compact, want %d puts 2 gets", m, puts)
+	}
+	if got := len(drainAll(t, q)); got != puts-2 {
+		t.Fatalf("%d items left, want %d", got, puts-2)
+	}
+}
+
+func TestSnapshotAndResetStatsConsistent(t *testing.T) {
+	q := newDefaultQueue(64, WithStats())
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for p := 0; p < 2; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// 每个数据先放入再取走，任何时刻取走的总数都不会超过放入的
+				if ok, _ := q.Put(1); ok {
+					for {
+						if _, ok, _ := q.Get(); ok {
+							break
+						}
+					}
+				}
+			}
+		}()
+	}
+
+	var sum QueueMetrics
+	for i := 0; i < 2000; i++ {
+		m := q.SnapshotAndResetStats()
+		sum.Puts += m.Puts
+		sum.Gets += m.Gets
+		if sum.Gets > sum.Puts {
+			close(stop)
+			wg.Wait()
+			t.Fatalf("snapshot %d: %d gets in total but only %d puts, counters not reset at the same instant", i, sum.Gets, sum.Puts)
+		}
+		if i%64 == 0 {
+			time.Sleep(time.Microsecond)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	// 每个操作只出现在一个快照中
+	m := q.SnapshotAndResetStats()
+	sum.Puts += m.Puts
+	sum.Gets += m.Gets
+	if sum.Gets != sum.Puts || q.Count() != 0 {
+		t.Fatalf("%d puts and %d gets over all snapshots, count %d", sum.Puts, sum.Gets, q.Count())
+	}
+}
+
+func TestContentionStats(t *testing.T) {
+	// 单核时 goroutine 只在让出的地方切换，Load 和 CAS 之间几乎不会被打断，见 TestContentionStatsStalePosition
+	if runtime.NumCPU() == 1 {
+		t.Skip("no CAS contention on a single CPU")
+	}
+	q := newDefaultQueue(8, WithStats())
+	// 很多生产者和消费者抢一个很小的队列，直到占位 CAS 都失败过
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				q.Put(1)
+				q.Get()
+			}
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	putRetries, getRetries := q.ContentionStats()
+	for (putRetries == 0 || getRetries == 0) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		putRetries, getRetries = q.ContentionStats()
+	}
+	close(stop)
+	wg.Wait()
+	if putRetries == 0 || getRetries == 0 {
+		t.Fatalf("retries put %d get %d under contention, want both > 0", putRetries, getRetries)
+	}
+
+	q.ResetContentionStats()
+	if p, g := q.ContentionStats(); p != 0 || g != 0 {
+		t.Fatalf("retries put %d get %d after reset", p, g)
+	}
+	// 没有竞争时不增加
+	q.Put(1)
+	q.Get()
+	if p, g := q.ContentionStats(); p != 0 || g != 0 {
+		t.Fatalf("retries put %d get %d without contention", p, g)
+	}
+}
+
+func TestContentionStatsStalePosition(t *testing.T) {
+	q := newDefaultQueue(8, WithStats())
+	// 另一个生产者在 Load 和 CAS 之间放入了数据
+	write := q.write.Load()
+	q.Put(1)
+	if q.casWrite(write, write+1) {
+		t.Fatal("CAS with a stale write succeeded")
+	}
+	read := q.read.Load()
+	q.Get()
+	if q.casRead(read, read+1) {
+		t.Fatal("CAS with a stale read succeeded")
+	}
+	if p, g := q.ContentionStats(); p != 1 || g != 1 {
+		t.Fatalf("retries put %d get %d, want 1 each", p, g)
+	}
+	q.ResetContentionStats()
+	if p, g := q.ContentionStats(); p != 0 || g != 0 {
+		t.Fatalf("retries put %d get %d after reset", p, g)
+	}
+}
+
+func TestContentionStatsNeedsStats(t *testing.T) {
+	q := newDefaultQueue(8)
+	// 模拟别的生产者、消费者先改了位置
+	if q.casWrite(5, 6) || q.casRead(5, 6) {
+		t.Fatal("CAS with a stale position succeeded")
+	}
+	if p, g := q.ContentionStats(); p != 0 || g != 0 {
+		t.Fatalf("retries put %d get %d without WithStats", p, g)
+	}
+}
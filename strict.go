@@ -0,0 +1,39 @@
+package queue
+
+/*
+ @File : strict.go
+ @Description: invariant checks for WithStrictChecks, panic on the violation of the usage
+ @Time : 2026/10/15
+*/
+
+import "fmt"
+
+// checkEpoch 操作结束时 Reset 的次数变了，说明 Reset 和 Put/Get 并发了
+func (q *DefaultQueue) checkEpoch(op string, epoch uint32) {
+	if q.resets.Load() != epoch {
+		panic(fmt.Sprintf("%s: strict check: Reset called during %s, Reset must not run concurrently with other operations", q.tag(), op))
+	}
+}
+
+// checkSlot 等待 pos 位置时检查槽的状态：writeID 只能比 readID 大 0 或 cap，
+// 而且已经占到的位置不可能被读写越过
+func (q *DefaultQueue) checkSlot(op string, pos, readID, writeID uint32) {
+	if q.dropAfter > 0 {
+		return // 放弃位置时 writeID 有中间状态，见 WithDropAfter
+	}
+	// readID 和 writeID 是分开读的，读 writeID 期间 readID 变了的话两者不是同一时刻的，不检查
+	if q.carrier[pos&q.capMod].readID.Load() != readID {
+		return
+	}
+	if d := writeID - readID; d != 0 && d != q.cap && d != q.cap+1 { // cap+1 是 Peek 钉住了槽，见 loadValue
+		panic(fmt.Sprintf("%s: strict check: %s pos %d, slot %d broken, writeID %d readID %d", q.tag(), op, pos, pos&q.capMod, writeID, readID))
+	}
+	id := writeID
+	if op == "Get" {
+		id = readID
+	}
+	if int32(pos-id) < 0 {
+		panic(fmt.Sprintf("%s: strict check: %s pos %d, slot %d already passed, writeID %d readID %d, Reset or copied queue?",
+			q.tag(), op, pos, pos&q.capMod, writeID, readID))
+	}
+}
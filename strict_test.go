@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// mustPanic fn 必须 panic，而且信息中包含 want
+func mustPanic(t *testing.T, want string, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		if r == nil {
+			t.Fatalf("no panic, want %q", want)
+		}
+		if msg := fmt.Sprint(r); !strings.Contains(msg, want) {
+			t.Fatalf("panic %q, want %q", msg, want)
+		}
+	}()
+	fn()
+}
+
+func TestStrictResetDuringGet(t *testing.T) {
+	var q *DefaultQueue
+	// 取数据的过程中 Reset，模拟和 Get 并发的 Reset
+	q = newDefaultQueue(8, WithID("orders"), WithStrictChecks(), WithCopyOnGet(func(v interface{}) interface{} {
+		q.Reset()
+		return v
+	}))
+	q.Put(1)
+	mustPanic(t, "orders: strict check: Reset called during Get", func() { q.Get() })
+}
+
+func TestStrictConcurrentConsumer(t *testing.T) {
+	q := NewMPSCQueue(8, WithStrictChecks()).(*MPSCQueue)
+	q.Put(1)
+	// read 已经被别的消费者改过，单消费者的 Store 会覆盖掉它
+	mustPanic(t, "concurrent consumers on a single consumer queue", func() { q.casRead(5, 6) })
+	if val, ok, _ := q.Get(); !ok || val != 1 {
+		t.Fatalf("get %v %v after the check", val, ok)
+	}
+}
+
+func TestStrictConcurrentProducer(t *testing.T) {
+	q := NewSPMCQueue(8, WithStrictChecks()).(*SPMCQueue)
+	mustPanic(t, "concurrent producers on a single producer queue", func() { q.casWrite(5, 6) })
+}
+
+func TestStrictBrokenSlot(t *testing.T) {
+	q := newDefaultQueue(8, WithStrictChecks())
+	q.Put(1)
+	// writeID 只能比 readID 大 0 或 cap
+	q.carrier[1].writeID.Store(q.carrier[1].readID.Load() + 3)
+	mustPanic(t, "slot 1 broken", func() { q.Get() })
+}
+
+func TestStrictPassedSlot(t *testing.T) {
+	q := newDefaultQueue(8, WithStrictChecks())
+	q.Put(1)
+	// 槽已经到了下一轮，Get 占到的位置永远等不到
+	next := 1 + q.cap
+	q.carrier[1].readID.Store(next)
+	q.carrier[1].writeID.Store(next)
+	mustPanic(t, "slot 1 already passed", func() { q.Get() })
+}
+
+func TestNoStrictChecks(t *testing.T) {
+	var q *DefaultQueue
+	q = newDefaultQueue(8, WithCopyOnGet(func(v interface{}) interface{} {
+		q.Reset()
+		return v
+	}))
+	q.Put(1)
+	// 没有开启时不检查
+	if val, ok, _ := q.Get(); !ok || val != 1 {
+		t.Fatalf("get %v %v", val, ok)
+	}
+}
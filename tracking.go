@@ -0,0 +1,76 @@
+package queue
+
+/*
+ @File : tracking.go
+ @Description: record who reserved the slots, for finding out the stalled producers, see WithCallerTracking
+ @Time : 2026/10/15
+*/
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// pkgPrefix 本包函数名的前缀，记录调用方时跳过本包的函数
+var pkgPrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name() // 形如 quequ.glob..func1
+	return name[:strings.Index(name, ".")+1]
+}()
+
+// ReserveLabel the same as Reserve, and record label as the owner of the position with WithCallerTracking,
+// StalledSlots report it if the token is not committed
+func (q *DefaultQueue) ReserveLabel(label string) (token *Token, ok bool) {
+	if token, ok = q.Reserve(); ok {
+		q.track(token.pos, label)
+	}
+	return token, ok
+}
+
+// StalledSlots list the positions which are reserved but not committed yet with who reserved them,
+// from head to tail, empty without WithCallerTracking. Best effort, the state changes while listing
+func (q *DefaultQueue) StalledSlots() []string {
+	if q.callers == nil {
+		return nil
+	}
+	var stalled []string
+	read := q.read.Load()
+	write := q.write.Load()
+	for pos := read + 1; pos != read+1+q.posCount(read, write); pos++ {
+		writeID := q.carrier[pos&q.capMod].writeID.Load()
+		// 提交后 writeID 至少为 pos+cap，见 Token.Committed
+		if int32(writeID-pos) > 0 {
+			continue
+		}
+		stalled = append(stalled, fmt.Sprintf("slot %d (pos %d): %s", pos&q.capMod, pos, q.callers[pos&q.capMod].Load()))
+	}
+	return stalled
+}
+
+// track 记录 pos 位置的占位方，label 为空时记录本包之外的第一个调用方
+func (q *DefaultQueue) track(pos uint32, label string) {
+	if q.callers == nil {
+		return
+	}
+	if label == "" {
+		label = callerLabel()
+	}
+	q.callers[pos&q.capMod].Store(label)
+}
+
+// callerLabel 调用栈中本包之外的第一个函数以及行号
+func callerLabel() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) {
+			return fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
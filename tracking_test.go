@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStalledSlotsReportLabel(t *testing.T) {
+	q := newDefaultQueue(8, WithCallerTracking())
+	q.Put(1)
+	stalled, _ := q.ReserveLabel("batch-writer")
+	q.Put(2)
+	auto, _ := q.Reserve() // 没有标签时记录调用方
+
+	slots := q.StalledSlots()
+	if len(slots) != 2 {
+		t.Fatalf("stalled slots %v, want 2", slots)
+	}
+	if !strings.Contains(slots[0], "pos 2") || !strings.HasSuffix(slots[0], ": batch-writer") {
+		t.Fatalf("stalled slot %q, want pos 2 labeled batch-writer", slots[0])
+	}
+	// 测试代码也在本包里，跳过后记录的是更外层的调用方，这里只检查有记录
+	if !strings.Contains(slots[1], "pos 4") || strings.HasSuffix(slots[1], ": ") {
+		t.Fatalf("stalled slot %q without caller", slots[1])
+	}
+
+	// 提交后不再报告
+	stalled.Commit("late")
+	auto.Commit("late")
+	if slots := q.StalledSlots(); len(slots) != 0 {
+		t.Fatalf("stalled slots %v after commit", slots)
+	}
+}
+
+func TestStalledSlotsNeedTracking(t *testing.T) {
+	q := newDefaultQueue(8)
+	q.ReserveLabel("x")
+	if slots := q.StalledSlots(); slots != nil {
+		t.Fatalf("stalled slots %v without WithCallerTracking", slots)
+	}
+}
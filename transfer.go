@@ -0,0 +1,118 @@
+package queue
+
+/*
+ @File : transfer.go
+ @Description: move items between queues in FIFO order
+ @Time : 2026/10/15
+*/
+
+// transferRetry Put 最多重试的次数，超过就认为 dst 满了
+const transferRetry = 64
+
+// spacer 能返回剩余空间的队列，DefaultQueue 以及内嵌它的变体队列都实现了
+type spacer interface {
+	space() uint32
+}
+
+// reserver 能先占一个写的位置再写入的队列，Transfer 靠它保证 dst 放得下才从 src 取，
+// DefaultQueue 以及内嵌它的变体队列、AdmissionQueue 实现了
+type reserver interface {
+	// reserveSlot 占一个写的位置，放不下（满、关闭、被拒绝）时 full 为 true，否则只是占位失败，可以重试
+	reserveSlot() (token *Token, full bool)
+}
+
+// Transfer get up to max items from src and put them into dst in the same order,
+// stop when src is empty or dst is full, return how many items transferred.
+//
+// A position of dst is reserved before each item leaves src, so no item is lost or reordered
+// even if dst is written by other producers at the same time.
+// dst must support reserving, that is a DefaultQueue, the variants embedding it or an AdmissionQueue,
+// nothing is transferred into other queues.
+// If src is emptied by other consumers after the position is reserved, a tombstone is written into it,
+// which is skipped by the consumers of dst
+func Transfer(dst, src Queue, max int) (n int) {
+	r, ok := dst.(reserver)
+	if !ok {
+		return 0
+	}
+	c, counted := src.(interface{ Count() uint32 })
+	for n < max {
+		// src 空了就不用去占 dst 的位置
+		if counted && c.Count() == 0 {
+			return n
+		}
+		token, full := r.reserveSlot()
+		if token == nil {
+			if full {
+				return n
+			}
+			continue // 占位失败，重试
+		}
+		val, ok := getRetry(src)
+		if !ok {
+			token.q.putAt(token.pos, entry{value: tombstone})
+			token.q.countPuts(1) // 消费者取走 tombstone 时也计入 Gets
+			return n
+		}
+		token.Commit(val)
+		n++
+	}
+	return n
+}
+
+// getRetry 只是占位失败时重试，空了返回 false
+func getRetry(q Queue) (val interface{}, ok bool) {
+	for {
+		val, ok, cnt := q.Get()
+		if ok {
+			return val, true
+		}
+		if cnt == 0 {
+			return nil, false
+		}
+	}
+}
+
+// reserveSlot see reserver
+func (q *DefaultQueue) reserveSlot() (token *Token, full bool) {
+	posNext, cnt, ok := q.reserveOne()
+	if ok {
+		return &Token{q: q, pos: posNext}, false
+	}
+	return nil, q.closed.Load() || cnt >= q.usable() || (q.spill != nil && q.spill.len() > 0)
+}
+
+// space 剩余可以 Put 的个数，关闭后为 0
+func (q *DefaultQueue) space() uint32 {
+	if q.closed.Load() {
+		return 0
+	}
+	cnt := q.posCount(q.read.Load(), q.write.Load())
+	usable := q.usable()
+	if cnt >= usable {
+		return 0
+	}
+	return usable - cnt
+}
+
+func hasSpace(q Queue) bool {
+	if s, ok := q.(spacer); ok {
+		return s.space() > 0
+	}
+	return true // 不知道剩余空间，由 putRetry 判断
+}
+
+// putRetry Put 失败时，如果还有空间说明只是占位失败，继续重试，
+// 最多重试 transferRetry 次，有空间 Put 却一直失败的队列（比如 AdmissionQueue 拒绝时）不会一直卡住
+func putRetry(q Queue, val interface{}) bool {
+	s, known := q.(spacer)
+	for i := 0; i < transferRetry; i++ {
+		if ok, _ := q.Put(val); ok {
+			return true
+		}
+		if known && s.space() == 0 {
+			return false
+		}
+	}
+	return false
+}
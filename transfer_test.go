@@ -0,0 +1,80 @@
+package queue
+
+import "testing"
+
+func TestTransferEqualCap(t *testing.T) {
+	src, dst := newDefaultQueue(16), newDefaultQueue(16)
+	src.PutSlice(ints(10))
+
+	if n := Transfer(dst, src, 4); n != 4 {
+		t.Fatalf("transferred %d, want 4", n)
+	}
+	// src 空了就停
+	if n := Transfer(dst, src, 100); n != 6 {
+		t.Fatalf("transferred %d, want 6", n)
+	}
+	assertSeq(t, drainAll(t, dst), 0, 10)
+}
+
+func TestTransferSmallerDst(t *testing.T) {
+	src, dst := newDefaultQueue(32), newDefaultQueue(8) // dst 最多 6 个
+	src.PutSlice(ints(20))
+
+	// dst 满了就停，没有数据丢失
+	if n := Transfer(dst, src, 100); n != 6 {
+		t.Fatalf("transferred %d, want 6", n)
+	}
+	assertSeq(t, drainAll(t, dst), 0, 6)
+	assertSeq(t, drainAll(t, src), 6, 14)
+}
+
+func TestTransferDstFilledByOthers(t *testing.T) {
+	src, dst := newDefaultQueue(16), newDefaultQueue(8)
+	src.PutSlice(ints(5))
+	dst.PutSlice([]interface{}{-1, -2, -3, -4, -5})
+
+	// dst 只剩一个位置，先占到位置才从 src 取，满了就停，src 剩下的顺序不变
+	if n := Transfer(dst, src, 5); n != 1 {
+		t.Fatalf("transferred %d, want 1", n)
+	}
+	assertSeq(t, drainAll(t, src), 1, 4)
+	if got := drainAll(t, dst); len(got) != 6 || got[5] != 0 {
+		t.Fatalf("dst is %v, want 0 at the tail", got)
+	}
+}
+
+// rejectQueue Put 总是失败，Get 总是空，也不知道数量
+type rejectQueue struct{}
+
+func (rejectQueue) Put(interface{}) (bool, uint32)   { return false, 0 }
+func (rejectQueue) Get() (interface{}, bool, uint32) { return nil, false, 0 }
+
+func TestTransferNotReservable(t *testing.T) {
+	src := newDefaultQueue(8)
+	src.PutSlice(ints(3))
+
+	// dst 不能先占位置，不从 src 取
+	if n := Transfer(rejectQueue{}, src, 3); n != 0 {
+		t.Fatalf("transferred %d, want 0", n)
+	}
+	assertSeq(t, drainAll(t, src), 0, 3)
+}
+
+func TestTransferSrcEmptied(t *testing.T) {
+	dst := newDefaultQueue(8)
+	dst.Put(1)
+
+	// 占了 dst 的位置后 src 是空的，写入 tombstone，dst 的消费者跳过它
+	if n := Transfer(dst, rejectQueue{}, 3); n != 0 {
+		t.Fatalf("transferred %d, want 0", n)
+	}
+	if val, ok, _ := dst.Get(); !ok || val != 1 {
+		t.Fatalf("get %v %v, want 1", val, ok)
+	}
+	if val, ok, _ := dst.Get(); ok {
+		t.Fatalf("get %v from the tombstone", val)
+	}
+	if cnt := dst.Count(); cnt != 0 {
+		t.Fatalf("count %d after the tombstone, want 0", cnt)
+	}
+}
@@ -0,0 +1,50 @@
+package queue
+
+/*
+ @File : ttl.go
+ @Description: queue whose items expire after a ttl, evicted lazily by Get
+ @Time : 2026/10/15
+*/
+
+import "time"
+
+// TTLQueue drop the items older than ttl, the time is recorded when the item is put.
+// No background goroutine, the expired items at the head are taken and discarded by Get,
+// the expired ones behind the head still take room until Get reaches them, see EvictOlderThan
+type TTLQueue struct {
+	ring *DefaultQueue
+	ttl  time.Duration
+}
+
+// NewTTLQueue alloc a TTLQueue, the items live for ttl after put
+func NewTTLQueue(cap uint32, ttl time.Duration) *TTLQueue {
+	return &TTLQueue{
+		ring: newDefaultQueue(cap, WithTimestamp()),
+		ttl:  ttl,
+	}
+}
+
+// Put May failed if lock slot failed or full
+func (q *TTLQueue) Put(val interface{}) (ok bool, count uint32) {
+	return q.ring.Put(val)
+}
+
+// Get take the first item which is not expired, the expired ones before it are discarded,
+// ok false if there is no live item or lock slot failed
+func (q *TTLQueue) Get() (val interface{}, ok bool, count uint32) {
+	for {
+		e, ok, cnt := q.ring.getEntry()
+		if !ok {
+			return nil, false, cnt
+		}
+		if time.Since(time.Unix(0, e.stamp())) <= q.ttl {
+			return e.value, true, cnt
+		}
+		// 过期了，已经从槽中取出，直接丢弃，继续看下一个
+	}
+}
+
+// Count the number of items in queue, include the expired ones not evicted yet
+func (q *TTLQueue) Count() uint32 {
+	return q.ring.Count()
+}
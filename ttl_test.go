@@ -0,0 +1,51 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLQueueSkipsExpired(t *testing.T) {
+	q := NewTTLQueue(8, 20*time.Millisecond)
+	q.Put("old1")
+	q.Put("old2")
+	time.Sleep(40 * time.Millisecond)
+	q.Put("live1")
+	q.Put("live2")
+
+	// 过期的在 Get 时取出丢弃，直接拿到第一个没过期的
+	if val, ok, cnt := q.Get(); !ok || val != "live1" || cnt != 1 {
+		t.Fatalf("get %v %v count %d, want live1 with 1 left", val, ok, cnt)
+	}
+	for i := range q.ring.carrier {
+		if v := q.ring.carrier[i].value; v == "old1" || v == "old2" {
+			t.Fatalf("expired %v still in slot %d", v, i)
+		}
+	}
+	if val, ok, _ := q.Get(); !ok || val != "live2" {
+		t.Fatalf("get %v %v, want live2", val, ok)
+	}
+
+	// 全部过期时返回空
+	q.Put("gone")
+	time.Sleep(40 * time.Millisecond)
+	if val, ok, cnt := q.Get(); ok || cnt != 0 {
+		t.Fatalf("get %v %v count %d, want empty after expiry", val, ok, cnt)
+	}
+}
+
+func TestTTLQueueLiveInOrder(t *testing.T) {
+	q := NewTTLQueue(16, time.Hour)
+	for i := 0; i < 10; i++ {
+		q.Put(i)
+	}
+	// 过期的还没取到之前也占着位置
+	if q.Count() != 10 {
+		t.Fatalf("count %d", q.Count())
+	}
+	for i := 0; i < 10; i++ {
+		if val, ok, _ := q.Get(); !ok || val != i {
+			t.Fatalf("get %v %v, want %d", val, ok, i)
+		}
+	}
+}